@@ -0,0 +1,219 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+)
+
+// CircuitBreaker configures a circuit breaker that stops sending requests to
+// Compute while it is failing, so that callers fail fast instead of piling up
+// requests during an outage.
+//
+// The circuit opens after FailureThreshold consecutive requests fail with a
+// transport error, a timeout or a 429 or 5xx response. Other errors, such as
+// a 404, a response that cannot be decoded or a canceled context, do not
+// count as failures. While the circuit is open, requests fail immediately
+// with an error matching ErrCircuitOpen. Once Cooldown has passed, the
+// circuit is half-open: a single probe request is let through, and each
+// successful probe allows one more concurrent probe, until SuccessThreshold
+// probes have succeeded and the circuit closes. A failed probe opens the
+// circuit again for another Cooldown.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed requests that
+	// opens the circuit. It must be at least 1.
+	FailureThreshold int
+
+	// Cooldown is how long the circuit stays open before probe requests are
+	// let through. It must be positive.
+	Cooldown time.Duration
+
+	// SuccessThreshold is the number of successful probe requests that close
+	// the circuit.
+	//
+	// Defaults to 3.
+	SuccessThreshold int
+}
+
+const defaultBreakerSuccessThreshold = 3
+
+// validate returns an error if b is not a valid circuit breaker
+// configuration. A nil configuration is valid.
+func (b *CircuitBreaker) validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.FailureThreshold < 1 {
+		return fmt.Errorf("compute: CircuitBreaker.FailureThreshold must be at least 1, got %d", b.FailureThreshold)
+	}
+	if b.Cooldown <= 0 {
+		return fmt.Errorf("compute: CircuitBreaker.Cooldown must be positive, got %v", b.Cooldown)
+	}
+	if b.SuccessThreshold < 0 {
+		return fmt.Errorf("compute: CircuitBreaker.SuccessThreshold must not be negative, got %d", b.SuccessThreshold)
+	}
+	return nil
+}
+
+// breakerState is the state of a circuit breaker. The values are those
+// recorded in the CircuitBreakerState metric.
+type breakerState int64
+
+const (
+	breakerClosed   breakerState = 0
+	breakerHalfOpen breakerState = 1
+	breakerOpen     breakerState = 2
+)
+
+// circuitBreaker implements CircuitBreaker. It is safe for concurrent use.
+type circuitBreaker struct {
+	failureThreshold int
+	cooldown         time.Duration
+	successThreshold int
+
+	mu             sync.Mutex
+	state          breakerState
+	failures       int       // consecutive failures while closed
+	openedAt       time.Time // when the circuit last opened
+	probes         int       // probes in flight while half-open
+	probeSuccesses int       // successful probes since half-open
+	now            func() time.Time
+}
+
+// newCircuitBreaker returns a closed circuitBreaker with the given settings,
+// or nil if b is nil.
+func newCircuitBreaker(b *CircuitBreaker, clk clock) *circuitBreaker {
+	if b == nil {
+		return nil
+	}
+	successThreshold := b.SuccessThreshold
+	if successThreshold == 0 {
+		successThreshold = defaultBreakerSuccessThreshold
+	}
+	return &circuitBreaker{
+		failureThreshold: b.FailureThreshold,
+		cooldown:         b.Cooldown,
+		successThreshold: successThreshold,
+		now:              clk.Now,
+	}
+}
+
+// allow reports whether a request may be sent, and whether it is a probe of
+// a half-open circuit. Each allowed request must be followed by a call to
+// done. A nil circuitBreaker allows every request.
+func (b *circuitBreaker) allow(ctx context.Context) (ok, probe bool) {
+	if b == nil {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerClosed:
+		return true, false
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.probes = 0
+		b.probeSuccesses = 0
+		b.setState(ctx, breakerHalfOpen)
+	}
+	// Let one more probe through for each probe that succeeded, so that
+	// traffic resumes gradually.
+	if b.probes > b.probeSuccesses {
+		return false, false
+	}
+	b.probes++
+	return true, true
+}
+
+// done records the outcome of a request allowed by allow.
+func (b *circuitBreaker) done(ctx context.Context, probe bool, err error) {
+	if b == nil {
+		return
+	}
+	failed := isBreakerFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		if b.state != breakerHalfOpen {
+			return
+		}
+		b.probes--
+		if failed {
+			b.open(ctx)
+			return
+		}
+		if b.probeSuccesses++; b.probeSuccesses >= b.successThreshold {
+			b.failures = 0
+			b.setState(ctx, breakerClosed)
+		}
+		return
+	}
+	if b.state != breakerClosed {
+		// The request was sent before the circuit opened.
+		return
+	}
+	if !failed {
+		b.failures = 0
+		return
+	}
+	if b.failures++; b.failures >= b.failureThreshold {
+		b.open(ctx)
+	}
+}
+
+// open opens the circuit. b.mu must be held.
+func (b *circuitBreaker) open(ctx context.Context) {
+	b.openedAt = b.now()
+	b.setState(ctx, breakerOpen)
+}
+
+// setState sets the state of the circuit and records it in the
+// CircuitBreakerState metric. b.mu must be held.
+func (b *circuitBreaker) setState(ctx context.Context, s breakerState) {
+	b.state = s
+	if isViewEnabled(CircuitBreakerStateView) {
+		stats.Record(ctx, CircuitBreakerState.M(int64(s)))
+	}
+}
+
+// isBreakerFailure reports whether err is a sign that Compute is degraded:
+// a transport error, such as a refused connection or a timeout, or a 429 or
+// 5xx response. Other errors, such as a 404, a response that cannot be
+// decoded or a request the caller canceled, are not.
+func isBreakerFailure(err error) bool {
+	if err == nil || xerrors.Is(err, context.Canceled) {
+		return false
+	}
+	var gerr *googleapi.Error
+	if xerrors.As(err, &gerr) {
+		return gerr.Code >= 500 || gerr.Code == http.StatusTooManyRequests
+	}
+	var uerr *url.Error
+	var nerr net.Error
+	return xerrors.As(err, &uerr) || xerrors.As(err, &nerr) || xerrors.Is(err, io.ErrUnexpectedEOF)
+}
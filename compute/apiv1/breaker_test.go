@@ -0,0 +1,196 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	if err := EnableCircuitBreakerStateView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableCircuitBreakerStateView()
+
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{CircuitBreaker: &CircuitBreaker{FailureThreshold: 2, Cooldown: time.Hour}}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, `{"error": {"code": 503, "message": "unavailable"}}`, http.StatusServiceUnavailable)
+	})
+	defer teardown()
+
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	noRetry := gax.WithRetry(func() gax.Retryer { return nil })
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(context.Background(), req, noRetry)
+		var gerr *googleapi.Error
+		if !xerrors.As(err, &gerr) || gerr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("call %d: got %v, want a 503", i, err)
+		}
+	}
+	if _, err := c.Get(context.Background(), req, noRetry); !xerrors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if calls != 2 {
+		t.Errorf("server received %d calls, want 2", calls)
+	}
+
+	rows, err := view.RetrieveData(CircuitBreakerStateView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Data.(*view.LastValueData).Value != float64(breakerOpen) {
+		t.Errorf("got rows %v, want the open state", rows)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	b := newCircuitBreaker(&CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute, SuccessThreshold: 2}, realClock{})
+	b.now = func() time.Time { return now }
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable}
+
+	ok, probe := b.allow(ctx)
+	if !ok || probe {
+		t.Fatalf("closed: allow = %v, %v, want true, false", ok, probe)
+	}
+	b.done(ctx, probe, unavailable)
+	if ok, _ := b.allow(ctx); ok {
+		t.Fatal("open: allow = true, want false")
+	}
+
+	// After the cooldown, a single probe is let through; a failed probe
+	// opens the circuit again.
+	now = now.Add(time.Minute)
+	if ok, probe := b.allow(ctx); !ok || !probe {
+		t.Fatalf("half-open: allow = %v, %v, want true, true", ok, probe)
+	}
+	if ok, _ := b.allow(ctx); ok {
+		t.Fatal("half-open: second concurrent probe allowed, want one at a time")
+	}
+	b.done(ctx, true, unavailable)
+	if ok, _ := b.allow(ctx); ok {
+		t.Fatal("reopened: allow = true, want false")
+	}
+
+	// Each successful probe allows one more concurrent probe, until
+	// SuccessThreshold probes have succeeded.
+	now = now.Add(time.Minute)
+	if ok, _ := b.allow(ctx); !ok {
+		t.Fatal("half-open: first probe not allowed")
+	}
+	b.done(ctx, true, nil)
+	for i := 0; i < 2; i++ {
+		if ok, probe := b.allow(ctx); !ok || !probe {
+			t.Fatalf("half-open: probe %d: allow = %v, %v, want true, true", i, ok, probe)
+		}
+	}
+	if ok, _ := b.allow(ctx); ok {
+		t.Fatal("half-open: third concurrent probe allowed, want two")
+	}
+	b.done(ctx, true, nil)
+	if b.state != breakerClosed {
+		t.Fatalf("state = %v, want closed", b.state)
+	}
+	b.done(ctx, true, nil)
+	if ok, probe := b.allow(ctx); !ok || probe {
+		t.Errorf("closed: allow = %v, %v, want true, false", ok, probe)
+	}
+}
+
+func TestIsBreakerFailure(t *testing.T) {
+	decodeErr := protojson.Unmarshal([]byte(`{"name": 1}`), &computepb.Disk{})
+	if decodeErr == nil {
+		t.Fatal("got nil error decoding an invalid disk")
+	}
+	for _, test := range []struct {
+		desc string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"503", &googleapi.Error{Code: http.StatusServiceUnavailable}, true},
+		{"429", &googleapi.Error{Code: http.StatusTooManyRequests}, true},
+		{"404", &googleapi.Error{Code: http.StatusNotFound}, false},
+		{"refused connection", &url.Error{Op: "Get", URL: "https://compute.googleapis.com", Err: syscall.ECONNREFUSED}, true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://compute.googleapis.com", Err: context.DeadlineExceeded}, true},
+		{"truncated body", io.ErrUnexpectedEOF, true},
+		{"canceled", context.Canceled, false},
+		{"canceled request", &url.Error{Op: "Get", URL: "https://compute.googleapis.com", Err: context.Canceled}, false},
+		{"decode error", decodeErr, false},
+		{"unknown enum", maybeUnknownEnum(decodeErr), false},
+	} {
+		if got := isBreakerFailure(test.err); got != test.want {
+			t.Errorf("%s: isBreakerFailure(%v) = %v, want %v", test.desc, test.err, got, test.want)
+		}
+	}
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	ctx := context.Background()
+	b := newCircuitBreaker(&CircuitBreaker{FailureThreshold: 1, Cooldown: time.Minute}, realClock{})
+	for _, err := range []error{&googleapi.Error{Code: http.StatusNotFound}, context.Canceled} {
+		b.done(ctx, false, err)
+	}
+	if ok, _ := b.allow(ctx); !ok {
+		t.Error("allow = false after client errors, want true")
+	}
+}
+
+func TestCircuitBreakerIgnoresDecodeErrors(t *testing.T) {
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{CircuitBreaker: &CircuitBreaker{FailureThreshold: 1, Cooldown: time.Hour}}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"name": 1}`))
+	})
+	defer teardown()
+
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	noRetry := gax.WithRetry(func() gax.Retryer { return nil })
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(context.Background(), req, noRetry); err == nil || xerrors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d: got %v, want a decode error", i, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("server received %d calls, want the circuit to stay closed", calls)
+	}
+}
+
+func TestCircuitBreakerInvalid(t *testing.T) {
+	for _, b := range []*CircuitBreaker{
+		{FailureThreshold: 0, Cooldown: time.Second},
+		{FailureThreshold: 1},
+		{FailureThreshold: 1, Cooldown: time.Second, SuccessThreshold: -1},
+	} {
+		if err := b.validate(); err == nil {
+			t.Errorf("CircuitBreaker %+v: got nil error, want error", *b)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"sync"
+
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// getGroup coalesces concurrent Get calls of the same disk into a single
+// request, as configured by DisksClientConfig.CoalesceGets.
+type getGroup struct {
+	mu    sync.Mutex
+	calls map[string]*getCall
+}
+
+// getCall is a Get request in flight, shared by the calls of the same disk
+// made while it is.
+type getCall struct {
+	done chan struct{}
+	disk *computepb.Disk
+	err  error
+	// canceled reports whether the request failed because the context of
+	// the call that sent it was done, an error the other calls must not
+	// share.
+	canceled bool
+}
+
+func newGetGroup() *getGroup {
+	return &getGroup{calls: make(map[string]*getCall)}
+}
+
+// do calls get, unless a call with the same key is already in flight, in
+// which case it waits for that call and returns a copy of its result. If the
+// call in flight fails because its own context is done, get is called again.
+func (g *getGroup) do(ctx context.Context, key string, get func() (*computepb.Disk, error)) (*computepb.Disk, error) {
+	for {
+		g.mu.Lock()
+		call, ok := g.calls[key]
+		if !ok {
+			call = &getCall{done: make(chan struct{})}
+			g.calls[key] = call
+			g.mu.Unlock()
+
+			call.disk, call.err = get()
+			call.canceled = call.err != nil && ctx.Err() != nil
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(call.done)
+			return call.disk, call.err
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if call.canceled {
+			continue
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		return proto.Clone(call.disk).(*computepb.Disk), nil
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+func TestCoalesceGets(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+		status   = http.StatusOK
+	)
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		code := status
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			w.Write([]byte(`{"name": "d", "labels": {"k": "v"}}`))
+		}
+	}
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{CoalesceGets: true}, handler)
+	defer teardown()
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	noRetry := gax.WithRetry(func() gax.Retryer { return nil })
+
+	// getAll makes n concurrent Gets once the first one sent its request.
+	getAll := func(n int) ([]*computepb.Disk, []error) {
+		disks := make([]*computepb.Disk, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		get := func(i int) {
+			defer wg.Done()
+			disks[i], errs[i] = c.Get(context.Background(), req, noRetry)
+		}
+		wg.Add(n)
+		go get(0)
+		<-started
+		for i := 1; i < n; i++ {
+			go get(i)
+		}
+		// Give the other Gets time to wait for the request in flight.
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		release = make(chan struct{})
+		return disks, errs
+	}
+
+	disks, errs := getAll(5)
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Get %d: %v", i, err)
+		}
+	}
+	disks[0].Labels["k"] = "changed"
+	for i, d := range disks[1:] {
+		if got := d.GetLabels()["k"]; got != "v" {
+			t.Errorf("disk %d: label = %q, want the disks not to be shared", i+1, got)
+		}
+	}
+
+	// Errors are shared too, and nothing is cached once the request completed.
+	mu.Lock()
+	requests, status = 0, http.StatusNotFound
+	mu.Unlock()
+	_, errs = getAll(3)
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("Get %d: got no error", i)
+		}
+	}
+
+	// A Get whose request fails because its context is done sends its own.
+	mu.Lock()
+	requests, status = 0, http.StatusOK
+	mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := c.Get(ctx, req, noRetry)
+		leader <- err
+	}()
+	<-started
+	follower := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), req, noRetry)
+		follower <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-leader; err == nil {
+		t.Error("got no error for a canceled Get")
+	}
+	<-started
+	close(release)
+	if err := <-follower; err != nil {
+		t.Errorf("got %v, want the Get to send its own request", err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// FieldDiff is a difference between the desired and the actual value of a
+// field of a Disk, as reported by DiffDisk.
+type FieldDiff struct {
+	// Field is the name of the field, such as "size_gb", or "labels.env" for
+	// a single label.
+	Field string
+
+	// Desired is the desired value, or "" if the field, such as a label,
+	// should be absent.
+	Desired string
+
+	// Actual is the actual value, or "" if the field is absent.
+	Actual string
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: want %q, got %q", d.Field, d.Desired, d.Actual)
+}
+
+// DiffDisk reports the differences between the desired state of a disk, such
+// as one built with DiskSpec, and its actual state, as returned by Get. It
+// compares the size, type, labels and resource policies of the disks, sorted
+// in that order. Fields that are not set in desired are not compared, and
+// output-only fields are ignored.
+//
+// Disk types and resource policies may be given either as names, such as
+// "pd-ssd", or as partial or full URLs; only their names are compared.
+func DiffDisk(desired, actual *computepb.Disk) []FieldDiff {
+	var diffs []FieldDiff
+	if desired.SizeGb != nil && desired.GetSizeGb() != actual.GetSizeGb() {
+		diffs = append(diffs, FieldDiff{
+			Field:   "size_gb",
+			Desired: fmt.Sprint(desired.GetSizeGb()),
+			Actual:  fmt.Sprint(actual.GetSizeGb()),
+		})
+	}
+	if desired.Type != nil && lastSegment(desired.GetType()) != lastSegment(actual.GetType()) {
+		diffs = append(diffs, FieldDiff{Field: "type", Desired: desired.GetType(), Actual: actual.GetType()})
+	}
+	if desired.Labels != nil {
+		diffs = append(diffs, diffLabels(desired.GetLabels(), actual.GetLabels())...)
+	}
+	if desired.ResourcePolicies != nil {
+		want := segmentSet(desired.GetResourcePolicies())
+		got := segmentSet(actual.GetResourcePolicies())
+		if want != got {
+			diffs = append(diffs, FieldDiff{Field: "resource_policies", Desired: want, Actual: got})
+		}
+	}
+	return diffs
+}
+
+// diffLabels returns a FieldDiff for each label that differs between want
+// and got, sorted by key.
+func diffLabels(want, got map[string]string) []FieldDiff {
+	keys := make(map[string]bool)
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range got {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	var diffs []FieldDiff
+	for _, k := range sorted {
+		w, wok := want[k]
+		g, gok := got[k]
+		if w != g || wok != gok {
+			diffs = append(diffs, FieldDiff{Field: "labels." + k, Desired: w, Actual: g})
+		}
+	}
+	return diffs
+}
+
+// segmentSet returns the sorted, comma-separated last path segments of the
+// given links.
+func segmentSet(links []string) string {
+	names := make([]string, len(links))
+	for i, l := range links {
+		names[i] = lastSegment(l)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// lastSegment returns the last path segment of link, which is the name of
+// the resource it refers to.
+func lastSegment(link string) string {
+	return link[strings.LastIndex(link, "/")+1:]
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDiffDisk(t *testing.T) {
+	actual := &computepb.Disk{
+		Name:              proto.String("d"),
+		SizeGb:            proto.Int64(10),
+		Type:              proto.String("https://www.googleapis.com/compute/v1/projects/p/zones/z/diskTypes/pd-ssd"),
+		Labels:            map[string]string{"env": "dev", "team": "storage", "extra": "x"},
+		ResourcePolicies:  []string{"https://www.googleapis.com/compute/v1/projects/p/regions/r/resourcePolicies/daily"},
+		Status:            proto.String("READY"),
+		CreationTimestamp: proto.String("2022-01-01T00:00:00Z"),
+	}
+
+	for _, test := range []struct {
+		desc    string
+		desired *computepb.Disk
+		want    []FieldDiff
+	}{
+		{
+			desc: "in sync",
+			desired: &computepb.Disk{
+				Name:             proto.String("d"),
+				SizeGb:           proto.Int64(10),
+				Type:             proto.String("zones/z/diskTypes/pd-ssd"),
+				Labels:           map[string]string{"env": "dev", "team": "storage", "extra": "x"},
+				ResourcePolicies: []string{"daily"},
+			},
+		},
+		{
+			desc:    "unset fields are not compared",
+			desired: &computepb.Disk{Name: proto.String("d")},
+		},
+		{
+			desc: "label and size drift",
+			desired: &computepb.Disk{
+				SizeGb: proto.Int64(20),
+				Labels: map[string]string{"env": "prod", "team": "storage", "owner": "me"},
+			},
+			want: []FieldDiff{
+				{Field: "size_gb", Desired: "20", Actual: "10"},
+				{Field: "labels.env", Desired: "prod", Actual: "dev"},
+				{Field: "labels.extra", Desired: "", Actual: "x"},
+				{Field: "labels.owner", Desired: "me", Actual: ""},
+			},
+		},
+		{
+			desc: "type and resource policy drift",
+			desired: &computepb.Disk{
+				Type:             proto.String("pd-balanced"),
+				ResourcePolicies: []string{},
+			},
+			want: []FieldDiff{
+				{Field: "type", Desired: "pd-balanced", Actual: "https://www.googleapis.com/compute/v1/projects/p/zones/z/diskTypes/pd-ssd"},
+				{Field: "resource_policies", Desired: "", Actual: "daily"},
+			},
+		},
+	} {
+		if diff := cmp.Diff(test.want, DiffDisk(test.desired, actual)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", test.desc, diff)
+		}
+	}
+}
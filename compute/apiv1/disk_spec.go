@@ -0,0 +1,240 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// diskNameRE matches valid disk names, as defined by RFC 1035.
+	diskNameRE = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+	// kmsKeyNameRE matches Cloud KMS key and key version resource names.
+	kmsKeyNameRE = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+(/cryptoKeyVersions/[1-9][0-9]*)?$`)
+
+	// zoneNameRE matches zone names, such as "us-central1-a".
+	zoneNameRE = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+)
+
+// DiskSpec builds the Disk resource of an InsertDiskRequest from commonly
+// used parameters, and validates it before any request is sent. For example:
+//
+//	req, err := compute.NewDiskSpec("my-disk").
+//		Zone("us-central1-a").
+//		FromImage("projects/debian-cloud/global/images/family/debian-11").
+//		SizeGB(100).
+//		Type("pd-ssd").
+//		InsertRequest("my-project")
+//
+// The setters can be chained; any error is reported by Disk or
+// InsertRequest. Fields that DiskSpec does not cover can be set on the
+// returned Disk.
+type DiskSpec struct {
+	name     string
+	zone     string
+	diskType string
+	sizeGB   int64
+	labels   map[string]string
+	sources  []string
+	kmsKey   string
+
+	disk computepb.Disk
+	err  error
+}
+
+// NewDiskSpec returns a DiskSpec for a disk with the given name.
+func NewDiskSpec(name string) *DiskSpec {
+	return &DiskSpec{name: name}
+}
+
+// Zone sets the zone the disk is created in. It is used to expand disk type
+// names and as the zone of the request built by InsertRequest.
+func (s *DiskSpec) Zone(zone string) *DiskSpec {
+	s.zone = zone
+	return s
+}
+
+// FromImage creates the disk from the given image, such as
+// "projects/debian-cloud/global/images/family/debian-11". It conflicts with
+// FromSnapshot and FromDisk.
+func (s *DiskSpec) FromImage(image string) *DiskSpec {
+	s.disk.SourceImage = proto.String(image)
+	s.sources = append(s.sources, "image")
+	return s
+}
+
+// FromSnapshot creates the disk from the given snapshot, such as
+// "global/snapshots/my-snapshot". It conflicts with FromImage and FromDisk.
+func (s *DiskSpec) FromSnapshot(snapshot string) *DiskSpec {
+	s.disk.SourceSnapshot = proto.String(snapshot)
+	s.sources = append(s.sources, "snapshot")
+	return s
+}
+
+// FromDisk creates the disk as a clone of the given disk, such as
+// "zones/us-central1-a/disks/my-disk". It conflicts with FromImage and
+// FromSnapshot.
+func (s *DiskSpec) FromDisk(disk string) *DiskSpec {
+	s.disk.SourceDisk = proto.String(disk)
+	s.sources = append(s.sources, "disk")
+	return s
+}
+
+// SizeGB sets the size of the disk in GB. When the disk is created from a
+// source, the size must not be less than the size of the source.
+func (s *DiskSpec) SizeGB(size int64) *DiskSpec {
+	if size <= 0 && s.err == nil {
+		s.err = fmt.Errorf("compute: disk size must be positive, got %d GB", size)
+	}
+	s.sizeGB = size
+	return s
+}
+
+// Type sets the disk type, either as a name such as "pd-ssd", which is
+// expanded to a URL in the zone set with Zone, or as the URL of a disk type
+// such as "zones/us-central1-a/diskTypes/pd-ssd".
+func (s *DiskSpec) Type(diskType string) *DiskSpec {
+	s.diskType = diskType
+	return s
+}
+
+// KMSKey encrypts the disk with the given customer-managed Cloud KMS key,
+// such as
+// "projects/p/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key".
+// To pin the disk to a single key version, pass the name of the key version
+// instead, such as
+// "projects/p/locations/us-central1/keyRings/my-ring/cryptoKeys/my-key/cryptoKeyVersions/1".
+func (s *DiskSpec) KMSKey(name string) *DiskSpec {
+	s.kmsKey = name
+	return s
+}
+
+// KMSKeyVersion encrypts the disk with the given version of the
+// customer-managed Cloud KMS key, which is named as for KMSKey.
+func (s *DiskSpec) KMSKeyVersion(key string, version int) *DiskSpec {
+	return s.KMSKey(fmt.Sprintf("%s/cryptoKeyVersions/%d", key, version))
+}
+
+// Labels sets the labels of the disk.
+func (s *DiskSpec) Labels(labels map[string]string) *DiskSpec {
+	s.labels = labels
+	return s
+}
+
+// Disk validates the spec and returns the Disk resource it describes.
+func (s *DiskSpec) Disk() (*computepb.Disk, error) {
+	return s.diskIn(s.zone)
+}
+
+// diskIn validates the spec and returns the Disk resource it describes, with
+// disk type names expanded in zone.
+func (s *DiskSpec) diskIn(zone string) (*computepb.Disk, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if !diskNameRE.MatchString(s.name) {
+		return nil, fmt.Errorf("compute: invalid disk name %q; names must be 1-63 characters long and match %s", s.name, diskNameRE)
+	}
+	if len(s.sources) > 1 {
+		return nil, fmt.Errorf("compute: conflicting disk sources %s; set at most one of image, snapshot and disk", strings.Join(s.sources, ", "))
+	}
+	if s.kmsKey != "" && !kmsKeyNameRE.MatchString(s.kmsKey) {
+		return nil, fmt.Errorf("compute: invalid Cloud KMS key name %q; want projects/PROJECT/locations/LOCATION/keyRings/RING/cryptoKeys/KEY, optionally followed by /cryptoKeyVersions/VERSION", s.kmsKey)
+	}
+	d := proto.Clone(&s.disk).(*computepb.Disk)
+	if s.kmsKey != "" {
+		d.DiskEncryptionKey = &computepb.CustomerEncryptionKey{KmsKeyName: proto.String(s.kmsKey)}
+	}
+	d.Name = proto.String(s.name)
+	if s.sizeGB > 0 {
+		d.SizeGb = proto.Int64(s.sizeGB)
+	}
+	if s.diskType != "" {
+		diskType := s.diskType
+		if !strings.Contains(diskType, "/") {
+			if zone == "" {
+				return nil, fmt.Errorf("compute: disk type %q is not a URL; set a Zone to expand it", diskType)
+			}
+			diskType = fmt.Sprintf("zones/%s/diskTypes/%s", zone, diskType)
+		}
+		d.Type = proto.String(diskType)
+	}
+	if len(s.labels) > 0 {
+		d.Labels = make(map[string]string, len(s.labels))
+		for k, v := range s.labels {
+			d.Labels[k] = v
+		}
+	}
+	return d, nil
+}
+
+// InsertRequest validates the spec and returns a request to create the disk
+// it describes in the given project and the zone set with Zone.
+func (s *DiskSpec) InsertRequest(project string) (*computepb.InsertDiskRequest, error) {
+	if s.zone == "" {
+		return nil, fmt.Errorf("compute: no zone set for disk %q", s.name)
+	}
+	d, err := s.Disk()
+	if err != nil {
+		return nil, err
+	}
+	return &computepb.InsertDiskRequest{
+		Project:      project,
+		Zone:         s.zone,
+		DiskResource: d,
+	}, nil
+}
+
+// InsertRequests validates the spec and returns one request per zone to
+// create the disk it describes in the given project and each of the given
+// zones, such as "us-central1-a", ignoring the zone set with Zone. Disk type
+// names are expanded in each zone, and the zone of a zonal disk type URL is
+// replaced by each zone. The requests can be sent concurrently, as they do
+// not share any messages.
+func (s *DiskSpec) InsertRequests(project string, zones ...string) ([]*computepb.InsertDiskRequest, error) {
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("compute: no zones given for disk %q", s.name)
+	}
+	seen := make(map[string]bool, len(zones))
+	reqs := make([]*computepb.InsertDiskRequest, 0, len(zones))
+	for _, zone := range zones {
+		if !zoneNameRE.MatchString(zone) {
+			return nil, fmt.Errorf("compute: invalid zone name %q; want a zone such as us-central1-a", zone)
+		}
+		if seen[zone] {
+			return nil, fmt.Errorf("compute: zone %q given more than once for disk %q", zone, s.name)
+		}
+		seen[zone] = true
+		d, err := s.diskIn(zone)
+		if err != nil {
+			return nil, err
+		}
+		if z := linkSegment(d.GetType(), "zones"); z != "" && z != zone {
+			d.Type = proto.String(strings.Replace(d.GetType(), "zones/"+z+"/", "zones/"+zone+"/", 1))
+		}
+		reqs = append(reqs, &computepb.InsertDiskRequest{
+			Project:      project,
+			Zone:         zone,
+			DiskResource: d,
+		})
+	}
+	return reqs, nil
+}
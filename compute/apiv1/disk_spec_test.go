@@ -0,0 +1,155 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestDiskSpecInsertRequest(t *testing.T) {
+	got, err := NewDiskSpec("my-disk").
+		Zone("us-central1-a").
+		FromImage("projects/debian-cloud/global/images/family/debian-11").
+		SizeGB(100).
+		Type("pd-ssd").
+		Labels(map[string]string{"env": "test"}).
+		InsertRequest("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &computepb.InsertDiskRequest{
+		Project: "p",
+		Zone:    "us-central1-a",
+		DiskResource: &computepb.Disk{
+			Name:        proto.String("my-disk"),
+			SourceImage: proto.String("projects/debian-cloud/global/images/family/debian-11"),
+			SizeGb:      proto.Int64(100),
+			Type:        proto.String("zones/us-central1-a/diskTypes/pd-ssd"),
+			Labels:      map[string]string{"env": "test"},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("InsertRequest mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiskSpecTypeURL(t *testing.T) {
+	d, err := NewDiskSpec("d").Type("projects/p/zones/z/diskTypes/pd-balanced").Disk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.GetType(), "projects/p/zones/z/diskTypes/pd-balanced"; got != want {
+		t.Errorf("Type = %q, want %q", got, want)
+	}
+}
+
+func TestDiskSpecKMSKey(t *testing.T) {
+	const key = "projects/p/locations/us-central1/keyRings/r/cryptoKeys/k"
+	for _, test := range []struct {
+		spec *DiskSpec
+		want string
+	}{
+		{NewDiskSpec("d").KMSKey(key), key},
+		{NewDiskSpec("d").KMSKey(key + "/cryptoKeyVersions/3"), key + "/cryptoKeyVersions/3"},
+		{NewDiskSpec("d").KMSKeyVersion(key, 2), key + "/cryptoKeyVersions/2"},
+	} {
+		d, err := test.spec.Disk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := d.GetDiskEncryptionKey().GetKmsKeyName(); got != test.want {
+			t.Errorf("KmsKeyName = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestDiskSpecErrors(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		spec *DiskSpec
+		want string
+	}{
+		{"conflicting sources", NewDiskSpec("d").FromImage("i").FromSnapshot("s"), "conflicting disk sources image, snapshot"},
+		{"invalid name", NewDiskSpec("My_Disk"), "invalid disk name"},
+		{"empty name", NewDiskSpec(""), "invalid disk name"},
+		{"non-positive size", NewDiskSpec("d").SizeGB(0), "size must be positive"},
+		{"type without zone", NewDiskSpec("d").Type("pd-ssd"), "set a Zone"},
+		{"invalid KMS key", NewDiskSpec("d").KMSKey("my-key"), "invalid Cloud KMS key name"},
+		{"invalid KMS key version", NewDiskSpec("d").KMSKey("projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/0"), "invalid Cloud KMS key name"},
+	} {
+		_, err := test.spec.Disk()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error containing %q", test.desc, err, test.want)
+		}
+	}
+	if _, err := NewDiskSpec("d").InsertRequest("p"); err == nil {
+		t.Error("InsertRequest without a zone: got nil, want an error")
+	}
+}
+
+func TestDiskSpecInsertRequests(t *testing.T) {
+	spec := NewDiskSpec("d").Zone("us-east1-b").SizeGB(10).Type("pd-ssd")
+	got, err := spec.InsertRequests("p", "us-central1-a", "europe-west4-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*computepb.InsertDiskRequest{
+		{
+			Project:      "p",
+			Zone:         "us-central1-a",
+			DiskResource: &computepb.Disk{Name: proto.String("d"), SizeGb: proto.Int64(10), Type: proto.String("zones/us-central1-a/diskTypes/pd-ssd")},
+		},
+		{
+			Project:      "p",
+			Zone:         "europe-west4-b",
+			DiskResource: &computepb.Disk{Name: proto.String("d"), SizeGb: proto.Int64(10), Type: proto.String("zones/europe-west4-b/diskTypes/pd-ssd")},
+		},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("InsertRequests mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = NewDiskSpec("d").Type("projects/p/zones/us-east1-b/diskTypes/pd-ssd").InsertRequests("p", "us-central1-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := got[0].GetDiskResource().GetType(), "projects/p/zones/us-central1-a/diskTypes/pd-ssd"; got != want {
+		t.Errorf("Type = %q, want %q", got, want)
+	}
+}
+
+func TestDiskSpecInsertRequestsErrors(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		zones []string
+		want  string
+	}{
+		{"no zones", nil, "no zones"},
+		{"region", []string{"us-central1"}, "invalid zone name"},
+		{"zone URL", []string{"zones/us-central1-a"}, "invalid zone name"},
+		{"duplicate zone", []string{"us-central1-a", "us-central1-a"}, "more than once"},
+	} {
+		_, err := NewDiskSpec("d").InsertRequests("p", test.zones...)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error containing %q", test.desc, err, test.want)
+		}
+	}
+}
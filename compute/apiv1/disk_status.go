@@ -0,0 +1,75 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// The statuses of a disk, as returned by computepb.Disk.GetStatus. They are
+// the names of the values of computepb.Disk_Status.
+const (
+	// DiskStatusCreating is the status of a disk that is being created.
+	DiskStatusCreating = "CREATING"
+	// DiskStatusRestoring is the status of a disk that is being restored
+	// from a snapshot.
+	DiskStatusRestoring = "RESTORING"
+	// DiskStatusReady is the status of a disk that is ready for use.
+	DiskStatusReady = "READY"
+	// DiskStatusFailed is the status of a disk whose creation failed.
+	DiskStatusFailed = "FAILED"
+	// DiskStatusDeleting is the status of a disk that is being deleted.
+	DiskStatusDeleting = "DELETING"
+)
+
+// IsDiskReady reports whether d is ready for use.
+func IsDiskReady(d *computepb.Disk) bool {
+	return d.GetStatus() == DiskStatusReady
+}
+
+// IsDiskFailed reports whether the creation of d failed.
+func IsDiskFailed(d *computepb.Disk) bool {
+	return d.GetStatus() == DiskStatusFailed
+}
+
+// IsDiskDeleting reports whether d is being deleted.
+func IsDiskDeleting(d *computepb.Disk) bool {
+	return d.GetStatus() == DiskStatusDeleting
+}
+
+// IsDiskStatusTerminal reports whether the status of d is one that it keeps
+// until it is changed or deleted by a request: READY or FAILED. Polling a disk
+// can stop once its status is terminal.
+func IsDiskStatusTerminal(d *computepb.Disk) bool {
+	switch d.GetStatus() {
+	case DiskStatusReady, DiskStatusFailed:
+		return true
+	}
+	return false
+}
+
+// IsDiskStatusTransient reports whether the status of d is one that changes
+// without further requests: CREATING, RESTORING or DELETING. A disk that is
+// DELETING ends up deleted rather than in another status.
+//
+// A disk with an empty status, or a status unknown to this package, is
+// neither terminal nor transient.
+func IsDiskStatusTransient(d *computepb.Disk) bool {
+	switch d.GetStatus() {
+	case DiskStatusCreating, DiskStatusRestoring, DiskStatusDeleting:
+		return true
+	}
+	return false
+}
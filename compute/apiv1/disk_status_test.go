@@ -0,0 +1,62 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"testing"
+
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDiskStatus(t *testing.T) {
+	for _, test := range []struct {
+		status                       string
+		enum                         computepb.Disk_Status
+		ready, failed, deleting      bool
+		terminal, transient, unknown bool
+	}{
+		{status: DiskStatusCreating, enum: computepb.Disk_CREATING, transient: true},
+		{status: DiskStatusRestoring, enum: computepb.Disk_RESTORING, transient: true},
+		{status: DiskStatusReady, enum: computepb.Disk_READY, ready: true, terminal: true},
+		{status: DiskStatusFailed, enum: computepb.Disk_FAILED, failed: true, terminal: true},
+		{status: DiskStatusDeleting, enum: computepb.Disk_DELETING, deleting: true, transient: true},
+		{status: "", unknown: true},
+		{status: "MIGRATING", unknown: true},
+	} {
+		if !test.unknown && test.status != test.enum.String() {
+			t.Errorf("%s: got enum value %s", test.status, test.enum)
+		}
+		d := &computepb.Disk{Status: proto.String(test.status)}
+		for _, p := range []struct {
+			name string
+			got  bool
+			want bool
+		}{
+			{"IsDiskReady", IsDiskReady(d), test.ready},
+			{"IsDiskFailed", IsDiskFailed(d), test.failed},
+			{"IsDiskDeleting", IsDiskDeleting(d), test.deleting},
+			{"IsDiskStatusTerminal", IsDiskStatusTerminal(d), test.terminal},
+			{"IsDiskStatusTransient", IsDiskStatusTransient(d), test.transient},
+		} {
+			if p.got != p.want {
+				t.Errorf("%s(%q) = %v, want %v", p.name, test.status, p.got, p.want)
+			}
+		}
+	}
+	if IsDiskReady(nil) || IsDiskStatusTerminal(nil) || IsDiskStatusTransient(nil) {
+		t.Error("got a status for a nil disk")
+	}
+}
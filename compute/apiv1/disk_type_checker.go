@@ -0,0 +1,160 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/iterator"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// defaultDiskTypesTTL is the default time the disk types of a zone are
+// cached by a DiskTypeChecker.
+const defaultDiskTypesTTL = 5 * time.Minute
+
+// DiskTypeChecker checks that disk types are available in a zone before a
+// disk is created, so that a disk type that a zone lacks, such as
+// "pd-extreme", is reported with a clear error instead of failing the
+// insert. It caches the disk types of each zone it lists. It is safe for
+// concurrent use.
+type DiskTypeChecker struct {
+	client *DiskTypesClient
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	zones map[string]diskTypesEntry
+}
+
+// diskTypesEntry holds the disk types available in a zone, as listed at the
+// given time.
+type diskTypesEntry struct {
+	types   []string
+	fetched time.Time
+}
+
+// NewDiskTypeChecker returns a DiskTypeChecker that lists the disk types of a
+// zone with client, and caches them for ttl. A ttl of 0 or less selects the
+// default of 5 minutes.
+func NewDiskTypeChecker(client *DiskTypesClient, ttl time.Duration) *DiskTypeChecker {
+	if ttl <= 0 {
+		ttl = defaultDiskTypesTTL
+	}
+	return &DiskTypeChecker{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+		zones:  make(map[string]diskTypesEntry),
+	}
+}
+
+// DiskTypeUnavailableError is returned by DiskTypeChecker when a disk type is
+// not available in a zone.
+type DiskTypeUnavailableError struct {
+	// Project and Zone are the project and zone the disk type was looked up
+	// in.
+	Project, Zone string
+
+	// DiskType is the name of the disk type, such as "pd-extreme".
+	DiskType string
+
+	// Available are the names of the disk types available in the zone, in
+	// alphabetical order.
+	Available []string
+}
+
+func (e *DiskTypeUnavailableError) Error() string {
+	return fmt.Sprintf("compute: disk type %q is not available in zone %q of project %q; available types are %s",
+		e.DiskType, e.Zone, e.Project, strings.Join(e.Available, ", "))
+}
+
+// Check returns a *DiskTypeUnavailableError if diskType is not available in
+// the given zone of the project. diskType is either a name, such as "pd-ssd",
+// or the URL of a zonal disk type, such as
+// "zones/us-central1-a/diskTypes/pd-ssd", whose zone takes precedence over
+// zone. Disk types that are deprecated as OBSOLETE or DELETED are not
+// available.
+func (c *DiskTypeChecker) Check(ctx context.Context, project, zone, diskType string) error {
+	name := diskType
+	if strings.Contains(diskType, "/") {
+		name = linkSegment(diskType, "diskTypes")
+		zone = linkSegment(diskType, "zones")
+		if name == "" || zone == "" {
+			return fmt.Errorf("compute: %q is not the URL of a zonal disk type", diskType)
+		}
+	}
+	if zone == "" {
+		return fmt.Errorf("compute: no zone to check disk type %q in", diskType)
+	}
+	types, err := c.diskTypes(ctx, project, zone)
+	if err != nil {
+		return err
+	}
+	if i := sort.SearchStrings(types, name); i < len(types) && types[i] == name {
+		return nil
+	}
+	return &DiskTypeUnavailableError{Project: project, Zone: zone, DiskType: name, Available: types}
+}
+
+// CheckSpec checks that the disk type of spec is available in the zone set
+// with spec.Zone, like Check. A spec without a disk type uses the default
+// type of Compute, and is not checked.
+func (c *DiskTypeChecker) CheckSpec(ctx context.Context, project string, spec *DiskSpec) error {
+	if spec.diskType == "" {
+		return nil
+	}
+	return c.Check(ctx, project, spec.zone, spec.diskType)
+}
+
+// diskTypes returns the sorted names of the disk types available in the
+// zone, from the cache if they were listed less than the TTL ago.
+func (c *DiskTypeChecker) diskTypes(ctx context.Context, project, zone string) ([]string, error) {
+	key := project + "/" + zone
+	c.mu.Lock()
+	e, ok := c.zones[key]
+	c.mu.Unlock()
+	if ok && c.now().Sub(e.fetched) < c.ttl {
+		return e.types, nil
+	}
+
+	var types []string
+	it := c.client.List(ctx, &computepb.ListDiskTypesRequest{Project: project, Zone: zone})
+	for {
+		t, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t.GetDeprecated().GetState() {
+		case "OBSOLETE", "DELETED":
+			continue
+		}
+		types = append(types, t.GetName())
+	}
+	sort.Strings(types)
+
+	c.mu.Lock()
+	c.zones[key] = diskTypesEntry{types: types, fetched: c.now()}
+	c.mu.Unlock()
+	return types, nil
+}
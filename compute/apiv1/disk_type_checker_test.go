@@ -0,0 +1,96 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/option"
+)
+
+func TestDiskTypeChecker(t *testing.T) {
+	var lists []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lists = append(lists, r.URL.Path)
+		w.Write([]byte(`{"items": [
+			{"name": "pd-standard"},
+			{"name": "pd-ssd"},
+			{"name": "pd-old", "deprecated": {"state": "OBSOLETE"}}
+		]}`))
+	}))
+	defer svr.Close()
+	ctx := context.Background()
+	client, err := NewDiskTypesRESTClient(ctx, option.WithEndpoint(svr.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	now := time.Now()
+	c := NewDiskTypeChecker(client, time.Minute)
+	c.now = func() time.Time { return now }
+
+	for _, diskType := range []string{"pd-ssd", "zones/z/diskTypes/pd-standard", "https://www.googleapis.com/compute/v1/projects/p/zones/z/diskTypes/pd-ssd"} {
+		if err := c.Check(ctx, "p", "z", diskType); err != nil {
+			t.Errorf("%s: %v", diskType, err)
+		}
+	}
+	for _, diskType := range []string{"pd-extreme", "pd-old"} {
+		err := c.Check(ctx, "p", "z", diskType)
+		var unavailable *DiskTypeUnavailableError
+		if !xerrors.As(err, &unavailable) {
+			t.Fatalf("%s: got %v, want a *DiskTypeUnavailableError", diskType, err)
+		}
+		if diff := cmp.Diff([]string{"pd-ssd", "pd-standard"}, unavailable.Available); diff != "" {
+			t.Errorf("%s: available types mismatch (-want +got):\n%s", diskType, diff)
+		}
+	}
+	if err := c.CheckSpec(ctx, "p", NewDiskSpec("d").Zone("z").Type("pd-extreme")); err == nil {
+		t.Error("got no error for a spec with an unavailable type")
+	}
+	if err := c.CheckSpec(ctx, "p", NewDiskSpec("d")); err != nil {
+		t.Errorf("got %v for a spec without a type", err)
+	}
+	if diff := cmp.Diff([]string{"/compute/v1/projects/p/zones/z/diskTypes"}, lists); diff != "" {
+		t.Errorf("lists mismatch (-want +got):\n%s", diff)
+	}
+
+	// The types are listed again once the TTL has passed.
+	now = now.Add(time.Minute)
+	if err := c.Check(ctx, "p", "z", "pd-ssd"); err != nil {
+		t.Fatal(err)
+	}
+	if len(lists) != 2 {
+		t.Errorf("got %d lists, want the types to be listed again after the TTL", len(lists))
+	}
+}
+
+func TestDiskTypeCheckerInvalid(t *testing.T) {
+	c := NewDiskTypeChecker(nil, 0)
+	for _, test := range []struct{ zone, diskType string }{
+		{"", "pd-ssd"},
+		{"z", "regions/r/diskTypes/pd-ssd"},
+	} {
+		if err := c.Check(context.Background(), "p", test.zone, test.diskType); err == nil {
+			t.Errorf("zone %q, type %q: got no error", test.zone, test.diskType)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// CreateAndAttachOptions configures CreateAndAttachDisk. A nil
+// *CreateAndAttachOptions, or a zero field, selects the default value.
+type CreateAndAttachOptions struct {
+	// AttachedDisk holds the settings of the attachment, such as its
+	// DeviceName or Mode. Its Source is set to the created disk.
+	//
+	// Defaults to nil, which attaches the disk with the defaults of Compute.
+	AttachedDisk *computepb.AttachedDisk
+
+	// KeepDiskOnFailure keeps the created disk if it cannot be attached to
+	// the instance.
+	//
+	// Defaults to false, which deletes the disk if attaching it fails.
+	KeepDiskOnFailure bool
+
+	// PollOptions configures how the creation of the disk and the attach
+	// operation are waited for.
+	PollOptions *PollOptions
+}
+
+// CreateAndAttachDisk creates the disk described by spec in the given project
+// and the zone set with spec.Zone, waits for it to be ready, attaches it to
+// the named instance in the same zone using instances, and waits for the
+// attach operation to be done. It returns the created disk as it was when
+// it became ready.
+//
+// If the disk cannot be attached, it is deleted, unless
+// opts.KeepDiskOnFailure is set, and the error of the attach is returned,
+// together with the error of the deletion, if any. The deletion uses ctx, so
+// it is not attempted once ctx is done.
+func (c *DisksClient) CreateAndAttachDisk(ctx context.Context, instances *InstancesClient, project string, spec *DiskSpec, instance string, opts *CreateAndAttachOptions) (*computepb.Disk, error) {
+	var o CreateAndAttachOptions
+	if opts != nil {
+		o = *opts
+	}
+	req, err := spec.InsertRequest(project)
+	if err != nil {
+		return nil, err
+	}
+	zone, name := req.GetZone(), req.GetDiskResource().GetName()
+	op, err := c.Insert(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.WaitForOperation(ctx, op, o.PollOptions); err != nil {
+		return nil, err
+	}
+	d, err := c.WaitForDiskReady(ctx, project, zone, name, o.PollOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	ad := &computepb.AttachedDisk{}
+	if o.AttachedDisk != nil {
+		ad = proto.Clone(o.AttachedDisk).(*computepb.AttachedDisk)
+	}
+	ad.Source = proto.String(d.GetSelfLink())
+	err = c.attachAndWait(ctx, instances, &computepb.AttachDiskInstanceRequest{
+		Project:              project,
+		Zone:                 zone,
+		Instance:             instance,
+		AttachedDiskResource: ad,
+	}, o.PollOptions)
+	if err == nil {
+		return d, nil
+	}
+	if !o.KeepDiskOnFailure {
+		if derr := c.deleteAndWait(ctx, project, zone, name, o.PollOptions); derr != nil {
+			return nil, xerrors.Errorf("compute: attaching disk %q to instance %q (deleting the disk also failed: %v): %w", name, instance, derr, err)
+		}
+	}
+	return nil, xerrors.Errorf("compute: attaching disk %q to instance %q: %w", name, instance, err)
+}
+
+// attachAndWait sends req and waits for its operation to be done.
+func (c *DisksClient) attachAndWait(ctx context.Context, instances *InstancesClient, req *computepb.AttachDiskInstanceRequest, po *PollOptions) error {
+	op, err := instances.AttachDisk(ctx, req)
+	if err != nil {
+		return err
+	}
+	_, err = c.WaitForOperation(ctx, op, po)
+	return err
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeAttachServer serves the requests of CreateAndAttachDisk, and records
+// them. Attaching fails if failAttach is set.
+type fakeAttachServer struct {
+	t          *testing.T
+	failAttach bool
+	requests   []string
+	attached   map[string]interface{}
+}
+
+func (s *fakeAttachServer) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/compute/v1/projects/p/zones/z/")
+	s.requests = append(s.requests, r.Method+" "+path)
+	const doneOp = `{"name": "op", "status": "DONE", "selfLink": "projects/p/zones/z/operations/op"}`
+	switch {
+	case r.Method == "POST" && path == "instances/i/attachDisk":
+		if s.failAttach {
+			http.Error(w, `{"error": {"code": 400, "message": "instance is busy"}}`, http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&s.attached); err != nil {
+			s.t.Error(err)
+		}
+		w.Write([]byte(doneOp))
+	case r.Method == "GET" && path == "disks/d":
+		w.Write([]byte(`{"name": "d", "status": "READY", "selfLink": "projects/p/zones/z/disks/d"}`))
+	default:
+		w.Write([]byte(doneOp))
+	}
+}
+
+func newFakeAttachClients(t *testing.T, s *fakeAttachServer) (*DisksClient, *InstancesClient, func()) {
+	svr := httptest.NewServer(http.HandlerFunc(s.handle))
+	opts := []option.ClientOption{option.WithEndpoint(svr.URL), option.WithoutAuthentication()}
+	disks, err := NewDisksRESTClient(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances, err := NewInstancesRESTClient(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return disks, instances, func() {
+		disks.Close()
+		instances.Close()
+		svr.Close()
+	}
+}
+
+func TestCreateAndAttachDisk(t *testing.T) {
+	s := &fakeAttachServer{t: t}
+	disks, instances, teardown := newFakeAttachClients(t, s)
+	defer teardown()
+
+	opts := &CreateAndAttachOptions{
+		AttachedDisk: &computepb.AttachedDisk{DeviceName: proto.String("data")},
+		PollOptions:  fastPoll,
+	}
+	d, err := disks.CreateAndAttachDisk(context.Background(), instances, "p", NewDiskSpec("d").Zone("z"), "i", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.GetName() != "d" {
+		t.Errorf("got disk %v, want d", d)
+	}
+	want := []string{
+		"POST disks",
+		"GET disks/d",
+		"POST instances/i/attachDisk",
+	}
+	if diff := cmp.Diff(want, s.requests); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+	wantAttached := map[string]interface{}{"deviceName": "data", "source": "projects/p/zones/z/disks/d"}
+	if diff := cmp.Diff(wantAttached, s.attached); diff != "" {
+		t.Errorf("attached disk mismatch (-want +got):\n%s", diff)
+	}
+	if opts.AttachedDisk.Source != nil {
+		t.Error("CreateAndAttachDisk modified opts.AttachedDisk")
+	}
+}
+
+func TestCreateAndAttachDiskFailure(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		s := &fakeAttachServer{t: t, failAttach: true}
+		disks, instances, teardown := newFakeAttachClients(t, s)
+
+		_, err := disks.CreateAndAttachDisk(context.Background(), instances, "p", NewDiskSpec("d").Zone("z"), "i",
+			&CreateAndAttachOptions{KeepDiskOnFailure: keep, PollOptions: fastPoll})
+		if err == nil || !strings.Contains(err.Error(), "instance is busy") {
+			t.Errorf("keep %v: got %v, want the error of the attach", keep, err)
+		}
+		deleted := s.requests[len(s.requests)-1] == "DELETE disks/d"
+		if deleted == keep {
+			t.Errorf("keep %v: got requests %v, want the disk to be deleted only if not kept", keep, s.requests)
+		}
+		teardown()
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/iterator"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// defaultDeleteConcurrency is the default number of disks
+// DeleteDisksByLabels deletes at once.
+const defaultDeleteConcurrency = 4
+
+// DeleteDisksOptions configures DeleteDisksByLabels. A nil
+// *DeleteDisksOptions, or a zero field, selects the default value.
+type DeleteDisksOptions struct {
+	// DryRun lists the disks that match without deleting any of them.
+	DryRun bool
+
+	// Concurrency is the largest number of disks that are deleted at once.
+	//
+	// Defaults to 4.
+	Concurrency int
+
+	// PollOptions configures how the delete operation of each disk is
+	// waited for.
+	PollOptions *PollOptions
+}
+
+// DeleteDiskResult is the result of DeleteDisksByLabels for a single disk.
+type DeleteDiskResult struct {
+	// Zone is the name of the zone of the disk.
+	Zone string
+
+	// Disk is the disk as it was listed before it was deleted.
+	Disk *computepb.Disk
+
+	// Deleted reports whether the disk was deleted. It is false for a dry
+	// run, and for a disk that was skipped or failed to be deleted.
+	Deleted bool
+
+	// Err is the reason the disk was not deleted: a *DiskInUseError if the
+	// disk was skipped because it is attached to instances, the error of
+	// the delete request or operation, or the error of ctx if the disk was
+	// not deleted before ctx was done. It is nil for a dry run.
+	Err error
+}
+
+// DeleteDisksByLabels deletes the disks in the given zone of the project that
+// have all of the given labels, and waits for them to be deleted. If zone is
+// empty, the disks in all zones of the project are deleted; regional disks
+// are not managed by DisksClient and are left alone.
+//
+// Disks that are attached to instances are skipped, and reported with a
+// *DiskInUseError. The results hold one entry for each disk that matched,
+// ordered by zone and name, with the outcome of deleting it. An error is
+// only returned if the disks could not be listed; errors deleting
+// individual disks are reported in their results.
+//
+// When ctx is done, no more deletes are started, and the disks not deleted
+// yet are reported with the error of ctx.
+func (c *DisksClient) DeleteDisksByLabels(ctx context.Context, project, zone string, labels map[string]string, opts *DeleteDisksOptions) ([]DeleteDiskResult, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("compute: no labels given to select the disks to delete")
+	}
+	var o DeleteDisksOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultDeleteConcurrency
+	}
+	filter, err := labelsFilter(labels)
+	if err != nil {
+		return nil, err
+	}
+	results, err := c.listForDelete(ctx, project, zone, filter)
+	if err != nil {
+		return nil, err
+	}
+	if o.DryRun {
+		return results, nil
+	}
+
+	sem := make(chan struct{}, o.Concurrency)
+	var wg sync.WaitGroup
+	for i := range results {
+		r := &results[i]
+		if r.Err = DiskInUse(r.Disk); r.Err != nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			r.Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.Err = c.deleteAndWait(ctx, project, r.Zone, r.Disk.GetName(), o.PollOptions)
+			r.Deleted = r.Err == nil
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// labelsFilter returns a filter expression that selects the resources with
+// all of the given labels.
+func labelsFilter(labels map[string]string) (string, error) {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	filters := make([]Filter, len(keys))
+	for i, k := range keys {
+		filters[i] = Eq("labels."+k, labels[k])
+	}
+	return And(filters...).Expr()
+}
+
+// listForDelete returns a result for each disk in the given zone of the
+// project, or in all zones if zone is empty, that matches filter, ordered by
+// zone and name.
+func (c *DisksClient) listForDelete(ctx context.Context, project, zone, filter string) ([]DeleteDiskResult, error) {
+	var results []DeleteDiskResult
+	if zone != "" {
+		it := c.List(ctx, &computepb.ListDisksRequest{Project: project, Zone: zone, Filter: &filter})
+		for {
+			d, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, DeleteDiskResult{Zone: zone, Disk: d})
+		}
+	} else {
+		it := c.AggregatedList(ctx, &computepb.AggregatedListDisksRequest{Project: project, Filter: &filter})
+		for {
+			pair, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(pair.Key, "zones/") {
+				continue
+			}
+			for _, d := range pair.Value.GetDisks() {
+				results = append(results, DeleteDiskResult{Zone: strings.TrimPrefix(pair.Key, "zones/"), Disk: d})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Zone != results[j].Zone {
+			return results[i].Zone < results[j].Zone
+		}
+		return results[i].Disk.GetName() < results[j].Disk.GetName()
+	})
+	return results, nil
+}
+
+// deleteAndWait deletes the disk and waits for the delete operation to be
+// done.
+func (c *DisksClient) deleteAndWait(ctx context.Context, project, zone, disk string, po *PollOptions) error {
+	op, err := c.Delete(ctx, &computepb.DeleteDiskRequest{Project: project, Zone: zone, Disk: disk})
+	if err != nil {
+		return err
+	}
+	_, err = c.WaitForOperation(ctx, op, po)
+	return err
+}
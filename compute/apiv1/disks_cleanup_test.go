@@ -0,0 +1,145 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+)
+
+// fakeDeleteServer serves the disks listed by its list handler, and records
+// the disks deleted through it. Deleting the disk named "fail" fails.
+type fakeDeleteServer struct {
+	list string
+
+	mu      sync.Mutex
+	filter  string
+	deleted []string
+}
+
+func (s *fakeDeleteServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "DELETE" {
+		disk := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if disk == "fail" {
+			http.Error(w, `{"error": {"code": 400, "message": "bad disk"}}`, http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.deleted = append(s.deleted, disk)
+		s.mu.Unlock()
+		fmt.Fprintf(w, `{"name": "op-%s", "status": "DONE", "selfLink": "projects/p/zones/z/operations/op-%s"}`, disk, disk)
+		return
+	}
+	s.mu.Lock()
+	s.filter = r.URL.Query().Get("filter")
+	s.mu.Unlock()
+	w.Write([]byte(s.list))
+}
+
+func TestDeleteDisksByLabels(t *testing.T) {
+	s := &fakeDeleteServer{list: `{"items": [
+		{"name": "b"},
+		{"name": "a"},
+		{"name": "attached", "users": ["projects/p/zones/z/instances/i"]},
+		{"name": "fail"}
+	]}`}
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, s.handle)
+	defer teardown()
+
+	results, err := c.DeleteDisksByLabels(context.Background(), "p", "z", map[string]string{"team": "x", "env": "test"}, &DeleteDisksOptions{Concurrency: 2, PollOptions: fastPoll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `(labels.env = "test") AND (labels.team = "x")`; s.filter != want {
+		t.Errorf("filter = %q, want %q", s.filter, want)
+	}
+	sort.Strings(s.deleted)
+	if diff := cmp.Diff([]string{"a", "b"}, s.deleted); diff != "" {
+		t.Errorf("deleted disks mismatch (-want +got):\n%s", diff)
+	}
+
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Disk.GetName())
+		if r.Zone != "z" {
+			t.Errorf("%s: zone = %q, want %q", r.Disk.GetName(), r.Zone, "z")
+		}
+		switch r.Disk.GetName() {
+		case "a", "b":
+			if !r.Deleted || r.Err != nil {
+				t.Errorf("%s: got deleted %v, error %v, want the disk to be deleted", r.Disk.GetName(), r.Deleted, r.Err)
+			}
+		case "attached":
+			var inUse *DiskInUseError
+			if r.Deleted || !xerrors.As(r.Err, &inUse) {
+				t.Errorf("%s: got deleted %v, error %v, want a *DiskInUseError", r.Disk.GetName(), r.Deleted, r.Err)
+			}
+		case "fail":
+			if r.Deleted || r.Err == nil || !strings.Contains(r.Err.Error(), "bad disk") {
+				t.Errorf("%s: got deleted %v, error %v, want the error of the delete", r.Disk.GetName(), r.Deleted, r.Err)
+			}
+		}
+	}
+	if diff := cmp.Diff([]string{"a", "attached", "b", "fail"}, names); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteDisksByLabelsDryRun(t *testing.T) {
+	s := &fakeDeleteServer{list: `{"items": {
+		"zones/z2": {"disks": [{"name": "c"}]},
+		"zones/z1": {"disks": [{"name": "b"}, {"name": "a"}]},
+		"regions/r": {"disks": [{"name": "regional"}]}
+	}}`}
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, s.handle)
+	defer teardown()
+
+	results, err := c.DeleteDisksByLabels(context.Background(), "p", "", map[string]string{"env": "test"}, &DeleteDisksOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.deleted) != 0 {
+		t.Errorf("deleted %v in a dry run", s.deleted)
+	}
+	var got []string
+	for _, r := range results {
+		if r.Deleted || r.Err != nil {
+			t.Errorf("%s: got deleted %v, error %v, want neither in a dry run", r.Disk.GetName(), r.Deleted, r.Err)
+		}
+		got = append(got, r.Zone+"/"+r.Disk.GetName())
+	}
+	if diff := cmp.Diff([]string{"z1/a", "z1/b", "z2/c"}, got); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeleteDisksByLabelsNoLabels(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	defer teardown()
+
+	if _, err := c.DeleteDisksByLabels(context.Background(), "p", "z", nil, nil); err == nil {
+		t.Error("got no error without labels")
+	}
+}
@@ -12,22 +12,26 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Code generated by protoc-gen-go_gapic. DO NOT EDIT.
+// This file was generated by protoc-gen-go_gapic, and has been maintained by
+// hand since: the disks methods send their requests through the shared
+// request path of disks_rest.go, configured by disks_config.go. Regenerating
+// the package must keep this file rather than overwrite it.
 
 package compute
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
+	"time"
 
 	gax "github.com/googleapis/gax-go/v2"
-	"google.golang.org/api/googleapi"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/api/option/internaloption"
@@ -35,7 +39,6 @@ import (
 	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -62,6 +65,8 @@ type DisksCallOptions struct {
 type internalDisksClient interface {
 	Close() error
 	setGoogleClientInfo(...string)
+	googleClientInfo() string
+	restHTTPClient() *http.Client
 	Connection() *grpc.ClientConn
 	AddResourcePolicies(context.Context, *computepb.AddResourcePoliciesDiskRequest, ...gax.CallOption) (*Operation, error)
 	AggregatedList(context.Context, *computepb.AggregatedListDisksRequest, ...gax.CallOption) *DisksScopedListPairIterator
@@ -76,6 +81,11 @@ type internalDisksClient interface {
 	SetIamPolicy(context.Context, *computepb.SetIamPolicyDiskRequest, ...gax.CallOption) (*computepb.Policy, error)
 	SetLabels(context.Context, *computepb.SetLabelsDiskRequest, ...gax.CallOption) (*Operation, error)
 	TestIamPermissions(context.Context, *computepb.TestIamPermissionsDiskRequest, ...gax.CallOption) (*computepb.TestPermissionsResponse, error)
+	getZoneOperation(ctx context.Context, project, zone, operation string) (*computepb.Operation, error)
+	locationTags(project, zone string) []tag.Mutator
+	doRaw(ctx context.Context, method, path string, body []byte) ([]byte, int, error)
+	logf(format string, v ...interface{})
+	systemClock() clock
 }
 
 // DisksClient is a client for interacting with Google Compute Engine API.
@@ -105,6 +115,22 @@ func (c *DisksClient) setGoogleClientInfo(keyval ...string) {
 	c.internalClient.setGoogleClientInfo(keyval...)
 }
 
+// GoogleClientInfo returns the value of the `x-goog-api-client` header
+// passed on each request. It identifies the versions of Go, this library,
+// gax and the transport, along with any pairs from
+// DisksClientConfig.ClientInfo.
+func (c *DisksClient) GoogleClientInfo() string {
+	return c.internalClient.googleClientInfo()
+}
+
+// HTTPClient returns the authenticated HTTP client the DisksClient sends its
+// requests with, for calling endpoints that DisksClient does not wrap.
+// Requests sent with it do not get the headers, retries or metrics of the
+// DisksClient methods. Modifying the returned client is not supported.
+func (c *DisksClient) HTTPClient() *http.Client {
+	return c.internalClient.restHTTPClient()
+}
+
 // Connection returns a connection to the API service.
 //
 // Deprecated.
@@ -187,45 +213,241 @@ type disksRESTClient struct {
 
 	// The x-goog-* metadata to be sent with each request.
 	xGoogMetadata metadata.MD
+
+	// The client configuration.
+	config DisksClientConfig
+
+	// The mutating methods that may be retried, keyed by RPC name.
+	retryMutating map[string]bool
+
+	// The budget shared by the retries of all calls, or nil if unlimited.
+	retryBudget *retryBudget
+
+	// The circuit breaker shared by all calls, or nil if disabled.
+	breaker *circuitBreaker
+
+	// The Get calls in flight, or nil if they are not coalesced.
+	gets *getGroup
+
+	// The path prefix of requests that is replaced with rebasedPath, or ""
+	// if DisksClientConfig.BasePath is not set.
+	apiPath     string
+	rebasedPath string
+
+	// Points back to the CallOptions field of the containing DisksClient
+	CallOptions **DisksCallOptions
+}
+
+// NewDisksClient creates a new disks client with the default transport,
+// which is REST.
+//
+// The Disks API.
+//
+// Unlike NewDisksRESTClient, it selects its transport with
+// DisksClientConfig.Transport when created with NewDisksClientWithConfig, so
+// that code creating clients with it is not tied to a transport.
+func NewDisksClient(ctx context.Context, opts ...option.ClientOption) (*DisksClient, error) {
+	return NewDisksClientWithConfig(ctx, DisksClientConfig{}, opts...)
+}
+
+// NewDisksClientWithConfig creates a new disks client with the provided
+// DisksClientConfig, using the transport selected by its Transport field.
+//
+// The Disks API.
+//
+// A transport that is not available, such as TransportGRPC for now, is
+// reported with an error matching ErrTransportUnavailable.
+func NewDisksClientWithConfig(ctx context.Context, config DisksClientConfig, opts ...option.ClientOption) (*DisksClient, error) {
+	switch config.Transport {
+	case TransportREST:
+		return NewDisksRESTClientWithConfig(ctx, config, opts...)
+	default:
+		return nil, xerrors.Errorf("compute: creating a disks client with transport %v: %w", config.Transport, ErrTransportUnavailable)
+	}
 }
 
 // NewDisksRESTClient creates a new disks rest client.
 //
 // The Disks API.
+//
+// The client requests the OAuth scopes returned by DefaultAuthScopes, unless
+// scopes are given with option.WithScopes, which replace the default scopes
+// entirely rather than being added to them. This allows a client to be
+// limited to narrower scopes, such as
+// "https://www.googleapis.com/auth/compute.readonly". Scopes do not apply to
+// credentials passed with option.WithTokenSource or option.WithCredentials,
+// which carry their own.
 func NewDisksRESTClient(ctx context.Context, opts ...option.ClientOption) (*DisksClient, error) {
-	clientOpts := append(defaultDisksRESTClientOptions(), opts...)
+	return NewDisksRESTClientWithConfig(ctx, DisksClientConfig{}, opts...)
+}
+
+// NewDisksRESTClientWithConfig creates a new disks rest client with the
+// provided DisksClientConfig.
+//
+// The Disks API.
+//
+// Scopes given with option.WithScopes replace the default scopes, as for
+// NewDisksRESTClient.
+func NewDisksRESTClientWithConfig(ctx context.Context, config DisksClientConfig, opts ...option.ClientOption) (*DisksClient, error) {
+	retryMutating, err := config.retryMutatingMethods()
+	if err != nil {
+		return nil, err
+	}
+	if len(config.ClientInfo)%2 != 0 {
+		return nil, fmt.Errorf("compute: ClientInfo must hold name/version pairs, got %d values", len(config.ClientInfo))
+	}
+	if err := config.RetryBudget.validate(); err != nil {
+		return nil, err
+	}
+	if err := config.CircuitBreaker.validate(); err != nil {
+		return nil, err
+	}
+	if err := validateRequestReason(config.RequestReason); err != nil {
+		return nil, err
+	}
+	if err := validateGzipRequestThreshold(config.GzipRequestThreshold); err != nil {
+		return nil, err
+	}
+	if err := validateBasePath(config.BasePath); err != nil {
+		return nil, err
+	}
+	if err := validateMinTLSVersion(config.MinTLSVersion); err != nil {
+		return nil, err
+	}
+	if err := validateReleaseID(config.ReleaseID); err != nil {
+		return nil, err
+	}
+	clientOpts := defaultDisksRESTClientOptions()
+	if o := config.resolveEndpoint(ctx, append(clientOpts, opts...)); o != nil {
+		clientOpts = append(clientOpts, o)
+	}
+	clientOpts = append(clientOpts, opts...)
 	httpClient, endpoint, err := httptransport.NewClient(ctx, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
+	if base := config.baseTransport(); base != nil {
+		if endpoint == defaultDisksMTLSEndpoint {
+			return nil, fmt.Errorf("compute: DialTimeout and MinTLSVersion cannot be used with the mTLS endpoint %s", endpoint)
+		}
+		trans, err := httptransport.NewTransport(ctx, base, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("compute: cannot apply DialTimeout or MinTLSVersion: %v", err)
+		}
+		httpClient = &http.Client{Transport: trans}
+	}
+	if config.RequestTimeout > 0 {
+		// Copy the client, which may have been passed by the caller with
+		// option.WithHTTPClient, rather than modify it.
+		hc := *httpClient
+		hc.Timeout = config.RequestTimeout
+		httpClient = &hc
+	}
+	httpClient = config.proactiveRefreshClient(httpClient)
+	httpClient = config.tokenLifetimeClient(httpClient)
 
+	callOpts := defaultDisksRESTCallOptions()
+	for _, name := range config.RetryMutatingMethods {
+		*callOpts.forMethod(name) = []gax.CallOption{defaultDisksRetry()}
+	}
+	client := &DisksClient{CallOptions: callOpts}
 	c := &disksRESTClient{
-		endpoint:   endpoint,
-		httpClient: httpClient,
+		endpoint:      endpoint,
+		httpClient:    httpClient,
+		config:        config,
+		retryMutating: retryMutating,
+		retryBudget:   newRetryBudget(config.RetryBudget, config.systemClock()),
+		breaker:       newCircuitBreaker(config.CircuitBreaker, config.systemClock()),
+		CallOptions:   &client.CallOptions,
+	}
+	if config.CoalesceGets {
+		c.gets = newGetGroup()
+	}
+	if config.BasePath != "" {
+		ep, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		c.apiPath = ep.Path + defaultBasePath
+		c.rebasedPath = ep.Path + strings.TrimSuffix(config.BasePath, "/")
 	}
-	c.setGoogleClientInfo()
+	c.setGoogleClientInfo(config.ClientInfo...)
+	client.internalClient = c
 
-	return &DisksClient{internalClient: c, CallOptions: &DisksCallOptions{}}, nil
+	return client, nil
 }
 
+// defaultDisksMTLSEndpoint is the endpoint disks clients use for mutual TLS.
+const defaultDisksMTLSEndpoint = "https://compute.mtls.googleapis.com"
+
 func defaultDisksRESTClientOptions() []option.ClientOption {
 	return []option.ClientOption{
 		internaloption.WithDefaultEndpoint("https://compute.googleapis.com"),
-		internaloption.WithDefaultMTLSEndpoint("https://compute.mtls.googleapis.com"),
+		internaloption.WithDefaultMTLSEndpoint(defaultDisksMTLSEndpoint),
 		internaloption.WithDefaultAudience("https://compute.googleapis.com/"),
 		internaloption.WithDefaultScopes(DefaultAuthScopes()...),
 	}
 }
 
+func defaultDisksRESTCallOptions() *DisksCallOptions {
+	return &DisksCallOptions{
+		AddResourcePolicies:    []gax.CallOption{},
+		AggregatedList:         []gax.CallOption{defaultDisksRetry()},
+		CreateSnapshot:         []gax.CallOption{},
+		Delete:                 []gax.CallOption{},
+		Get:                    []gax.CallOption{defaultDisksRetry()},
+		GetIamPolicy:           []gax.CallOption{defaultDisksRetry()},
+		Insert:                 []gax.CallOption{},
+		List:                   []gax.CallOption{defaultDisksRetry()},
+		RemoveResourcePolicies: []gax.CallOption{},
+		Resize:                 []gax.CallOption{},
+		SetIamPolicy:           []gax.CallOption{},
+		SetLabels:              []gax.CallOption{},
+		TestIamPermissions:     []gax.CallOption{defaultDisksRetry()},
+	}
+}
+
+// defaultDisksRetry is the retry setting used by the idempotent disks methods,
+// and by mutating methods named in DisksClientConfig.RetryMutatingMethods.
+func defaultDisksRetry() gax.CallOption {
+	return gax.WithRetry(func() gax.Retryer {
+		return onHTTPCodes(gax.Backoff{
+			Initial:    100 * time.Millisecond,
+			Max:        60000 * time.Millisecond,
+			Multiplier: 1.30,
+		},
+			http.StatusGatewayTimeout,
+			http.StatusServiceUnavailable)
+	})
+}
+
 // setGoogleClientInfo sets the name and version of the application in
 // the `x-goog-api-client` header passed on each request. Intended for
 // use by Google-written clients.
 func (c *disksRESTClient) setGoogleClientInfo(keyval ...string) {
 	kv := append([]string{"gl-go", versionGo()}, keyval...)
-	kv = append(kv, "gapic", versionClient, "gax", gax.Version, "rest", "UNKNOWN")
+	if c.config.ReleaseID != "" {
+		kv = append(kv, "release", c.config.ReleaseID)
+	}
+	restVersion := c.config.RESTVersion
+	if restVersion == "" {
+		restVersion = versionREST()
+	}
+	kv = append(kv, "gapic", versionClient, "gax", gax.Version, "rest", restVersion)
 	c.xGoogMetadata = metadata.Pairs("x-goog-api-client", gax.XGoogHeader(kv...))
 }
 
+// googleClientInfo returns the value of the `x-goog-api-client` header
+// passed on each request.
+func (c *disksRESTClient) googleClientInfo() string {
+	return c.xGoogMetadata.Get("x-goog-api-client")[0]
+}
+
+// restHTTPClient returns the HTTP client the REST client sends requests with.
+func (c *disksRESTClient) restHTTPClient() *http.Client {
+	return c.httpClient
+}
+
 // Close closes the connection to the API service. The user should invoke this when
 // the client is no longer required.
 func (c *disksRESTClient) Close() error {
@@ -243,13 +465,12 @@ func (c *disksRESTClient) Connection() *grpc.ClientConn {
 
 // AddResourcePolicies adds existing resource policies to a disk. You can only add one policy which will be applied to this disk for scheduling snapshot creation.
 func (c *disksRESTClient) AddResourcePolicies(ctx context.Context, req *computepb.AddResourcePoliciesDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetDisksAddResourcePoliciesRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).AddResourcePolicies[0:len((*c.CallOptions).AddResourcePolicies):len((*c.CallOptions).AddResourcePolicies)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.AddResourcePoliciesDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/addResourcePolicies", req.GetProject(), req.GetZone(), req.GetDisk())
 
@@ -260,48 +481,31 @@ func (c *disksRESTClient) AddResourcePolicies(ctx context.Context, req *computep
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.addResourcePolicies", "POST", baseUrl, req.GetDisksAddResourcePoliciesRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // AggregatedList retrieves an aggregated list of persistent disks.
 func (c *disksRESTClient) AggregatedList(ctx context.Context, req *computepb.AggregatedListDisksRequest, opts ...gax.CallOption) *DisksScopedListPairIterator {
+	opts = append((*c.CallOptions).AggregatedList[0:len((*c.CallOptions).AggregatedList):len((*c.CallOptions).AggregatedList)], opts...)
 	it := &DisksScopedListPairIterator{}
+	r, defaultsErr := c.withDefaults(req)
+	if defaultsErr == nil {
+		req = r.(*computepb.AggregatedListDisksRequest)
+	}
 	req = proto.Clone(req).(*computepb.AggregatedListDisksRequest)
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	it.InternalFetch = func(pageSize int, pageToken string) ([]DisksScopedListPair, string, error) {
+		if defaultsErr != nil {
+			return nil, "", defaultsErr
+		}
+		if c.config.ValidateOrderBy {
+			if err := validateDiskOrderBy(req.GetOrderBy()); err != nil {
+				return nil, "", err
+			}
+		}
 		resp := &computepb.DiskAggregatedList{}
 		if pageToken != "" {
 			req.PageToken = proto.String(pageToken)
@@ -333,37 +537,17 @@ func (c *disksRESTClient) AggregatedList(ctx context.Context, req *computepb.Agg
 		if req != nil && req.ReturnPartialSuccess != nil {
 			params.Add("returnPartialSuccess", fmt.Sprintf("%v", req.GetReturnPartialSuccess()))
 		}
-
-		baseUrl.RawQuery = params.Encode()
-
-		httpReq, err := http.NewRequest("GET", baseUrl.String(), nil)
-		if err != nil {
-			return nil, "", err
-		}
-
-		// Set the headers
-		for k, v := range c.xGoogMetadata {
-			httpReq.Header[k] = v
-		}
-
-		httpReq.Header["Content-Type"] = []string{"application/json"}
-		httpRsp, err := c.httpClient.Do(httpReq)
-		if err != nil {
-			return nil, "", err
+		if !c.config.PrettyPrint {
+			params.Add("prettyPrint", "false")
 		}
-		defer httpRsp.Body.Close()
 
-		if err = googleapi.CheckResponse(httpRsp); err != nil {
-			return nil, "", err
-		}
+		baseUrl.RawQuery = params.Encode()
 
-		buf, err := ioutil.ReadAll(httpRsp.Body)
-		if err != nil {
+		if err := c.do(ctx, "compute.disks.aggregatedList", "GET", baseUrl, nil, resp, opts...); err != nil {
 			return nil, "", err
 		}
-
-		unm.Unmarshal(buf, resp)
 		it.Response = resp
+		recordAggregatedScopes(ctx, req.GetProject(), len(resp.GetItems()), len(resp.GetUnreachables()))
 
 		elems := make([]DisksScopedListPair, 0, len(resp.GetItems()))
 		for k, v := range resp.GetItems() {
@@ -392,13 +576,12 @@ func (c *disksRESTClient) AggregatedList(ctx context.Context, req *computepb.Agg
 
 // CreateSnapshot creates a snapshot of a specified persistent disk.
 func (c *disksRESTClient) CreateSnapshot(ctx context.Context, req *computepb.CreateSnapshotDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetSnapshotResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).CreateSnapshot[0:len((*c.CallOptions).CreateSnapshot):len((*c.CallOptions).CreateSnapshot)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.CreateSnapshotDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/createSnapshot", req.GetProject(), req.GetZone(), req.GetDisk())
 
@@ -412,44 +595,21 @@ func (c *disksRESTClient) CreateSnapshot(ctx context.Context, req *computepb.Cre
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.createSnapshot", "POST", baseUrl, req.GetSnapshotResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // Delete deletes the specified persistent disk. Deleting a disk removes its data permanently and is irreversible. However, deleting a disk does not delete any snapshots previously made from the disk. You must separately delete snapshots.
 func (c *disksRESTClient) Delete(ctx context.Context, req *computepb.DeleteDiskRequest, opts ...gax.CallOption) (*Operation, error) {
+	opts = append((*c.CallOptions).Delete[0:len((*c.CallOptions).Delete):len((*c.CallOptions).Delete)], opts...)
+	r, err := c.withDefaults(req)
+	if err != nil {
+		return nil, err
+	}
+	req = r.(*computepb.DeleteDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v", req.GetProject(), req.GetZone(), req.GetDisk())
 
@@ -460,84 +620,49 @@ func (c *disksRESTClient) Delete(ctx context.Context, req *computepb.DeleteDiskR
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("DELETE", baseUrl.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.delete", "DELETE", baseUrl, nil, rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // Get returns a specified persistent disk. Gets a list of available persistent disks by making a list() request.
 func (c *disksRESTClient) Get(ctx context.Context, req *computepb.GetDiskRequest, opts ...gax.CallOption) (*computepb.Disk, error) {
-	baseUrl, _ := url.Parse(c.endpoint)
-	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v", req.GetProject(), req.GetZone(), req.GetDisk())
-
-	httpReq, err := http.NewRequest("GET", baseUrl.String(), nil)
+	opts = append((*c.CallOptions).Get[0:len((*c.CallOptions).Get):len((*c.CallOptions).Get)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
+	req = r.(*computepb.GetDiskRequest)
+	baseUrl, _ := url.Parse(c.endpoint)
+	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v", req.GetProject(), req.GetZone(), req.GetDisk())
 
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
+	get := func() (*computepb.Disk, error) {
+		rsp := &computepb.Disk{}
+		if err := c.do(ctx, "compute.disks.get", "GET", baseUrl, nil, rsp, opts...); err != nil {
+			return nil, err
+		}
+		return rsp, nil
 	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
+	if c.gets == nil {
+		return get()
 	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
+	reason, err := c.config.requestReason(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
-	rsp := &computepb.Disk{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
-	}
-	return rsp, nil
+	return c.gets.do(ctx, baseUrl.Path+"\x00"+reason, get)
 }
 
 // GetIamPolicy gets the access control policy for a resource. May be empty if no such policy or resource exists.
 func (c *disksRESTClient) GetIamPolicy(ctx context.Context, req *computepb.GetIamPolicyDiskRequest, opts ...gax.CallOption) (*computepb.Policy, error) {
+	opts = append((*c.CallOptions).GetIamPolicy[0:len((*c.CallOptions).GetIamPolicy):len((*c.CallOptions).GetIamPolicy)], opts...)
+	r, err := c.withDefaults(req)
+	if err != nil {
+		return nil, err
+	}
+	req = r.(*computepb.GetIamPolicyDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/getIamPolicy", req.GetProject(), req.GetZone(), req.GetResource())
 
@@ -548,50 +673,21 @@ func (c *disksRESTClient) GetIamPolicy(ctx context.Context, req *computepb.GetIa
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("GET", baseUrl.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Policy{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.getIamPolicy", "GET", baseUrl, nil, rsp, opts...); err != nil {
+		return nil, err
 	}
 	return rsp, nil
 }
 
 // Insert creates a persistent disk in the specified project using the data in the request. You can create a disk from a source (sourceImage, sourceSnapshot, or sourceDisk) or create an empty 500 GB data disk by omitting all properties. You can also create a disk that is larger than the default size by specifying the sizeGb property.
 func (c *disksRESTClient) Insert(ctx context.Context, req *computepb.InsertDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetDiskResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).Insert[0:len((*c.CallOptions).Insert):len((*c.CallOptions).Insert)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.InsertDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks", req.GetProject(), req.GetZone())
 
@@ -605,48 +701,35 @@ func (c *disksRESTClient) Insert(ctx context.Context, req *computepb.InsertDiskR
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.insert", "POST", baseUrl, req.GetDiskResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // List retrieves a list of persistent disks contained within the specified zone.
 func (c *disksRESTClient) List(ctx context.Context, req *computepb.ListDisksRequest, opts ...gax.CallOption) *DiskIterator {
+	opts = append((*c.CallOptions).List[0:len((*c.CallOptions).List):len((*c.CallOptions).List)], opts...)
 	it := &DiskIterator{}
+	r, defaultsErr := c.withDefaults(req)
+	if defaultsErr == nil {
+		req = r.(*computepb.ListDisksRequest)
+	}
 	req = proto.Clone(req).(*computepb.ListDisksRequest)
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
+	var sizer *pageSizer
+	if c.config.AdaptivePageSize && req.MaxResults == nil {
+		sizer = newPageSizer()
+	}
 	it.InternalFetch = func(pageSize int, pageToken string) ([]*computepb.Disk, string, error) {
+		if defaultsErr != nil {
+			return nil, "", defaultsErr
+		}
+		if c.config.ValidateOrderBy {
+			if err := validateDiskOrderBy(req.GetOrderBy()); err != nil {
+				return nil, "", err
+			}
+		}
 		resp := &computepb.DiskList{}
 		if pageToken != "" {
 			req.PageToken = proto.String(pageToken)
@@ -655,6 +738,8 @@ func (c *disksRESTClient) List(ctx context.Context, req *computepb.ListDisksRequ
 			req.MaxResults = proto.Uint32(math.MaxInt32)
 		} else if pageSize != 0 {
 			req.MaxResults = proto.Uint32(uint32(pageSize))
+		} else if sizer != nil {
+			req.MaxResults = proto.Uint32(sizer.size)
 		}
 		baseUrl, _ := url.Parse(c.endpoint)
 		baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks", req.GetProject(), req.GetZone())
@@ -675,36 +760,19 @@ func (c *disksRESTClient) List(ctx context.Context, req *computepb.ListDisksRequ
 		if req != nil && req.ReturnPartialSuccess != nil {
 			params.Add("returnPartialSuccess", fmt.Sprintf("%v", req.GetReturnPartialSuccess()))
 		}
-
-		baseUrl.RawQuery = params.Encode()
-
-		httpReq, err := http.NewRequest("GET", baseUrl.String(), nil)
-		if err != nil {
-			return nil, "", err
+		if !c.config.PrettyPrint {
+			params.Add("prettyPrint", "false")
 		}
 
-		// Set the headers
-		for k, v := range c.xGoogMetadata {
-			httpReq.Header[k] = v
-		}
+		baseUrl.RawQuery = params.Encode()
 
-		httpReq.Header["Content-Type"] = []string{"application/json"}
-		httpRsp, err := c.httpClient.Do(httpReq)
-		if err != nil {
+		start := time.Now()
+		if err := c.do(ctx, "compute.disks.list", "GET", baseUrl, nil, resp, opts...); err != nil {
 			return nil, "", err
 		}
-		defer httpRsp.Body.Close()
-
-		if err = googleapi.CheckResponse(httpRsp); err != nil {
-			return nil, "", err
+		if sizer != nil && pageSize == 0 {
+			sizer.observe(len(resp.GetItems()), time.Since(start))
 		}
-
-		buf, err := ioutil.ReadAll(httpRsp.Body)
-		if err != nil {
-			return nil, "", err
-		}
-
-		unm.Unmarshal(buf, resp)
 		it.Response = resp
 		return resp.GetItems(), resp.GetNextPageToken(), nil
 	}
@@ -727,13 +795,12 @@ func (c *disksRESTClient) List(ctx context.Context, req *computepb.ListDisksRequ
 
 // RemoveResourcePolicies removes resource policies from a disk.
 func (c *disksRESTClient) RemoveResourcePolicies(ctx context.Context, req *computepb.RemoveResourcePoliciesDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetDisksRemoveResourcePoliciesRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).RemoveResourcePolicies[0:len((*c.CallOptions).RemoveResourcePolicies):len((*c.CallOptions).RemoveResourcePolicies)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.RemoveResourcePoliciesDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/removeResourcePolicies", req.GetProject(), req.GetZone(), req.GetDisk())
 
@@ -744,51 +811,21 @@ func (c *disksRESTClient) RemoveResourcePolicies(ctx context.Context, req *compu
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.removeResourcePolicies", "POST", baseUrl, req.GetDisksRemoveResourcePoliciesRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // Resize resizes the specified persistent disk. You can only increase the size of the disk.
 func (c *disksRESTClient) Resize(ctx context.Context, req *computepb.ResizeDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetDisksResizeRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).Resize[0:len((*c.CallOptions).Resize):len((*c.CallOptions).Resize)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.ResizeDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/resize", req.GetProject(), req.GetZone(), req.GetDisk())
 
@@ -799,98 +836,39 @@ func (c *disksRESTClient) Resize(ctx context.Context, req *computepb.ResizeDiskR
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.resize", "POST", baseUrl, req.GetDisksResizeRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // SetIamPolicy sets the access control policy on the specified resource. Replaces any existing policy.
 func (c *disksRESTClient) SetIamPolicy(ctx context.Context, req *computepb.SetIamPolicyDiskRequest, opts ...gax.CallOption) (*computepb.Policy, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetZoneSetPolicyRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).SetIamPolicy[0:len((*c.CallOptions).SetIamPolicy):len((*c.CallOptions).SetIamPolicy)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.SetIamPolicyDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/setIamPolicy", req.GetProject(), req.GetZone(), req.GetResource())
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Policy{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.setIamPolicy", "POST", baseUrl, req.GetZoneSetPolicyRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
 	return rsp, nil
 }
 
 // SetLabels sets the labels on a disk. To learn more about labels, read the Labeling Resources documentation.
 func (c *disksRESTClient) SetLabels(ctx context.Context, req *computepb.SetLabelsDiskRequest, opts ...gax.CallOption) (*Operation, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetZoneSetLabelsRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).SetLabels[0:len((*c.CallOptions).SetLabels):len((*c.CallOptions).SetLabels)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.SetLabelsDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/setLabels", req.GetProject(), req.GetZone(), req.GetResource())
 
@@ -901,85 +879,27 @@ func (c *disksRESTClient) SetLabels(ctx context.Context, req *computepb.SetLabel
 
 	baseUrl.RawQuery = params.Encode()
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.Operation{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.setLabels", "POST", baseUrl, req.GetZoneSetLabelsRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
-	op := &Operation{proto: rsp}
-	return op, err
+	return &Operation{proto: rsp}, nil
 }
 
 // TestIamPermissions returns permissions that a caller has on the specified resource.
 func (c *disksRESTClient) TestIamPermissions(ctx context.Context, req *computepb.TestIamPermissionsDiskRequest, opts ...gax.CallOption) (*computepb.TestPermissionsResponse, error) {
-	m := protojson.MarshalOptions{AllowPartial: true}
-	body := req.GetTestPermissionsRequestResource()
-	jsonReq, err := m.Marshal(body)
+	opts = append((*c.CallOptions).TestIamPermissions[0:len((*c.CallOptions).TestIamPermissions):len((*c.CallOptions).TestIamPermissions)], opts...)
+	r, err := c.withDefaults(req)
 	if err != nil {
 		return nil, err
 	}
-
+	req = r.(*computepb.TestIamPermissionsDiskRequest)
 	baseUrl, _ := url.Parse(c.endpoint)
 	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/disks/%v/testIamPermissions", req.GetProject(), req.GetZone(), req.GetResource())
 
-	httpReq, err := http.NewRequest("POST", baseUrl.String(), bytes.NewReader(jsonReq))
-	if err != nil {
-		return nil, err
-	}
-	httpReq = httpReq.WithContext(ctx)
-	// Set the headers
-	for k, v := range c.xGoogMetadata {
-		httpReq.Header[k] = v
-	}
-	httpReq.Header["Content-Type"] = []string{"application/json"}
-
-	httpRsp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, err
-	}
-	defer httpRsp.Body.Close()
-
-	if err = googleapi.CheckResponse(httpRsp); err != nil {
-		return nil, err
-	}
-
-	buf, err := ioutil.ReadAll(httpRsp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 	rsp := &computepb.TestPermissionsResponse{}
-
-	if err := unm.Unmarshal(buf, rsp); err != nil {
-		return nil, maybeUnknownEnum(err)
+	if err := c.do(ctx, "compute.disks.testIamPermissions", "POST", baseUrl, req.GetTestPermissionsRequestResource(), rsp, opts...); err != nil {
+		return nil, err
 	}
 	return rsp, nil
 }
@@ -0,0 +1,682 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	"google.golang.org/api/transport"
+)
+
+// DisksClientConfig has configurations for the disks REST client. The zero
+// value is the configuration used by NewDisksRESTClient.
+type DisksClientConfig struct {
+	// ValidateOrderBy enables client-side validation of the OrderBy field of
+	// List and AggregatedList requests. When set, an orderBy value that
+	// Compute does not support is reported by the iterator before any request
+	// is sent, instead of as a 400 from the server.
+	//
+	// Defaults to false.
+	ValidateOrderBy bool
+
+	// RetryMutatingMethods names the mutating DisksClient methods, such as
+	// "Insert" or "Delete", that are retried on transient errors.
+	//
+	// Only the idempotent methods AggregatedList, Get, GetIamPolicy, List
+	// and TestIamPermissions are retried by default. Retry settings passed to
+	// any other method are ignored unless the method is named here, so that a
+	// retried request cannot silently create a duplicate resource. Requests to
+	// a named method that accepts a RequestId and do not set one are sent
+	// with a generated RequestId, which lets Compute recognize a retried
+	// request as a duplicate of the original.
+	RetryMutatingMethods []string
+
+	// Logger is used to log diagnostic events, such as each retried request
+	// with its method, attempt number, error and backoff delay. If nil,
+	// nothing is logged.
+	Logger *log.Logger
+
+	// ClientInfo holds additional name/version pairs, such as
+	// []string{"myapp", "1.2.0"}, that identify the application in the
+	// `x-goog-api-client` header passed on each request. It must have an
+	// even number of elements.
+	ClientInfo []string
+
+	// ReleaseID identifies the release or deployment of the application,
+	// such as "v1.4.2" or "2022-06-01.3", so that the requests it sends
+	// can be attributed to a rollout in Google-side logs and in the logs of
+	// proxies. It is sent in the `x-goog-api-client` header of each
+	// request, as the version of a "release" entry. It must be at most 128
+	// characters long, of ASCII letters, digits, '.', '_', '-' and '+'.
+	//
+	// Defaults to "", which sends no release entry.
+	ReleaseID string
+
+	// RESTVersion overrides the version reported for the REST transport in
+	// the `x-goog-api-client` header. By default it is the version of the
+	// google.golang.org/api module recorded in the binary's build
+	// information, or "UNKNOWN" if that is not available.
+	RESTVersion string
+
+	// PrettyPrint requests pretty-printed JSON responses to List and
+	// AggregatedList. By default these requests are sent with
+	// prettyPrint=false, so that the server returns compact responses, which
+	// are considerably smaller for large listings.
+	//
+	// Defaults to false.
+	PrettyPrint bool
+
+	// IncludeRequestInErrors wraps the errors of failed requests in a
+	// *RequestError, which records the HTTP method and URL of the request,
+	// so that an error such as a 404 can be traced to the resource path it
+	// was returned for. Credentials are removed from the recorded URL. The
+	// original error is still matched by errors.Is and errors.As, but not by
+	// comparing it with ==.
+	//
+	// Defaults to false.
+	IncludeRequestInErrors bool
+
+	// IncludeResponseInErrors wraps the errors of failed requests in a
+	// *RequestError like IncludeRequestInErrors, and also records the status
+	// line and headers of the response to the last attempt, if any, in it.
+	// This keeps the headers of failed responses alive for as long as their
+	// errors are, so it is off by default. The response body is not
+	// retained; its error message is available through *googleapi.Error.
+	//
+	// Defaults to false.
+	IncludeResponseInErrors bool
+
+	// ContentSHA256 attaches a checksum of the request body to the requests
+	// of mutating methods with a body, such as Insert and SetLabels. The
+	// checksum is sent in the X-Goog-Content-SHA256 header as the lowercase
+	// hex encoding of the SHA-256 digest of the bytes of the body sent on
+	// the wire: the compressed body when GzipRequestThreshold applies, and
+	// the JSON body otherwise. Methods without a body, such as Delete, send
+	// no checksum.
+	//
+	// The Compute API ignores this header and does not verify the body
+	// against it. It is only useful with an intermediate proxy of your own
+	// that checks it.
+	//
+	// Defaults to false.
+	ContentSHA256 bool
+
+	// GzipRequestThreshold, if positive, compresses the JSON request bodies
+	// of at least this many bytes with gzip, and sends them with a
+	// Content-Encoding: gzip header. Smaller bodies, where compression
+	// costs more than it saves, are sent as is. The checksum sent for
+	// ContentSHA256 is that of the compressed body.
+	//
+	// Only methods with a body, such as Insert and SetLabels, are affected.
+	// Compression is opt-in, as not every endpoint or proxy a client may be
+	// configured with accepts compressed request bodies.
+	//
+	// Defaults to 0, which sends every body uncompressed.
+	GzipRequestThreshold int
+
+	// RetryBudget, if set, limits the rate of retries across all concurrent
+	// calls of the client, so that a brief backend failure is not amplified
+	// into a retry storm. A call that would retry when the budget is
+	// exhausted fails immediately with an error matching
+	// ErrRetryBudgetExhausted instead.
+	//
+	// Defaults to nil, which places no limit on retries.
+	RetryBudget *RetryBudget
+
+	// CheckDeadlines reports each call made with a context that has no
+	// deadline, a common cause of hung calls, by writing a message to Logger
+	// and recording it in the MissingDeadlineCount metric, if enabled. The
+	// call itself proceeds as usual.
+	//
+	// Defaults to false.
+	CheckDeadlines bool
+
+	// AdaptivePageSize lets List choose the page size of a listing whose
+	// request does not set MaxResults, and whose iterator is not given a page
+	// size with iterator.NewPager. The first page holds up to 100 disks. The
+	// page size then doubles after each full page that took less than 1s to
+	// fetch, and halves after each page that took more than 4s, staying
+	// between 10 and 500, the largest page size Compute allows. This reduces
+	// the number of round trips of large listings without making individual
+	// pages slow.
+	//
+	// Defaults to false, which leaves the page size to Compute.
+	AdaptivePageSize bool
+
+	// LocationMetricTags tags the metrics of this package that are recorded
+	// for requests and operations, such as InFlightRequests and
+	// OperationWaitDuration, with the project and zone they target, in
+	// addition to their other tags. The tag keys are "project" and "zone".
+	// This adds a time series per project and zone, so it is best suited to
+	// clients that use a bounded number of them.
+	//
+	// Defaults to false.
+	LocationMetricTags bool
+
+	// DialTimeout is the longest time to wait for a connection to Compute to
+	// be established, so that calls fail fast when Compute is unreachable.
+	// It is independent of RequestTimeout and of the deadline of the context
+	// of a call. Setting it replaces the default HTTP transport of the client
+	// with one that does not present client certificates for mutual TLS, so
+	// like MinTLSVersion it cannot be combined with option.WithHTTPClient,
+	// nor with the mTLS endpoint.
+	//
+	// Defaults to 0, which leaves the dial timeout of the default transport.
+	DialTimeout time.Duration
+
+	// MinTLSVersion is the minimum TLS version the client accepts when
+	// connecting to Compute, such as tls.VersionTLS12 or tls.VersionTLS13,
+	// so that the version does not depend on the defaults of the Go
+	// release the client is built with. Like DialTimeout, setting it
+	// replaces the default HTTP transport of the client with one that does
+	// not present client certificates, so it cannot be combined with
+	// option.WithHTTPClient, nor with the mTLS endpoint, which requires a
+	// client certificate: creating a client that would use the mTLS
+	// endpoint, selected with option.WithEndpoint or by
+	// GOOGLE_API_USE_MTLS_ENDPOINT and GOOGLE_API_USE_CLIENT_CERTIFICATE,
+	// fails.
+	//
+	// Defaults to 0, which leaves the minimum version of the default
+	// transport.
+	MinTLSVersion uint16
+
+	// RequestTimeout is the longest time an HTTP request may take, from
+	// dialing to reading the last byte of the response body. Each attempt of
+	// a retried call has its own RequestTimeout. Set it generously to allow
+	// for large List responses, and use DialTimeout to detect an unreachable
+	// Compute quickly.
+	//
+	// Defaults to 0, which means no timeout other than the deadline of the
+	// context of a call.
+	RequestTimeout time.Duration
+
+	// CircuitBreaker, if set, stops sending requests while Compute is
+	// failing, so that they fail fast with ErrCircuitOpen instead of piling
+	// up. See CircuitBreaker for how the circuit opens and closes.
+	//
+	// Defaults to nil, which disables the circuit breaker.
+	CircuitBreaker *CircuitBreaker
+
+	// DefaultProject and DefaultZone are the project and zone used by
+	// requests that leave their Project or Zone field empty. A value set on
+	// a request always takes precedence. A request whose project or zone is
+	// set neither on the request nor here fails without being sent.
+	DefaultProject string
+	DefaultZone    string
+
+	// EndpointResolver, if set, chooses the endpoint the client connects to,
+	// for example the Compute endpoint nearest to where the project of the
+	// client's credentials is located. It is called once, when the client is
+	// created, with the project ID of the credentials the client will use.
+	//
+	// The static default, https://compute.googleapis.com, is used instead if
+	// the credentials have no project ID, or if EndpointResolver returns an
+	// error or an empty endpoint; the error, if any, is written to Logger.
+	// An endpoint passed with option.WithEndpoint always takes precedence
+	// over the resolved one.
+	EndpointResolver func(ctx context.Context, projectID string) (string, error)
+
+	// BasePath replaces the "/compute/v1" prefix of the paths of the
+	// requests of the client, including the paths passed to RawRequest that
+	// start with it, so that the client can be pointed at a Compute
+	// emulator or mock that serves the API under another path. It must start
+	// with a slash; "/" serves the API at the root of the endpoint. The host
+	// is set with option.WithEndpoint, and any path of the endpoint is kept
+	// in front of BasePath.
+	//
+	// Defaults to "", which uses "/compute/v1".
+	BasePath string
+
+	// RejectUnknownFields makes calls fail when a response holds a field
+	// that the version of the Compute protos used by the client does not
+	// know, instead of ignoring the field. This is meant for tests that
+	// detect when Compute adds fields the client does not model yet; in
+	// production, it makes calls fail whenever Compute adds a field.
+	//
+	// Defaults to false.
+	RejectUnknownFields bool
+
+	// RequestReason is a justification for the calls of the client, such as
+	// a ticket number, which Compute records in its audit logs. It is sent
+	// in the X-Goog-Request-Reason header. A reason set for a single call
+	// with WithRequestReason takes precedence. It must be at most 512
+	// printable ASCII characters.
+	//
+	// Defaults to "", which sends no reason.
+	RequestReason string
+
+	// ProactiveTokenRefresh, if positive, makes the client fetch a new
+	// access token in the background once its token expires within
+	// ProactiveTokenRefresh, instead of in the first request made after the
+	// token expired, which then waits for the refresh. Requests keep using
+	// the current token until a new one is fetched, unless it is about to
+	// expire. Background refresh errors are written to Logger.
+	//
+	// It applies to the token source the client would use otherwise: the
+	// one of the credentials passed with an option such as
+	// option.WithCredentialsFile or option.WithTokenSource, or else of
+	// Application Default Credentials. The token sources of Application
+	// Default Credentials, like those made with oauth2.ReuseTokenSource,
+	// cache their tokens and only fetch a new one in the last 10 seconds of
+	// the lifetime of the cached one, so with them, a token is refreshed at
+	// most 10 seconds before it expires, whatever the value of
+	// ProactiveTokenRefresh. A token source passed with
+	// option.WithTokenSource that fetches a new token on each call is
+	// refreshed as early as configured. It has no effect on clients that do
+	// not authenticate with a token source, such as those created with
+	// option.WithoutAuthentication or option.WithAPIKey, or with an
+	// http.Client passed with option.WithHTTPClient whose Transport is not
+	// an *oauth2.Transport.
+	//
+	// Defaults to 0, which refreshes tokens when they expire.
+	ProactiveTokenRefresh time.Duration
+
+	// TokenLifetimeMetrics makes the client record the remaining lifetime
+	// of the access token each request is sent with, as the
+	// TokenRemainingLifetime metric, while TokenRemainingLifetimeView is
+	// enabled. Like ProactiveTokenRefresh, it only applies to clients that
+	// authenticate with a token source, and it is applied to the tokens
+	// refreshed proactively if both are set. Tokens without an expiry are
+	// not recorded.
+	//
+	// Defaults to false.
+	TokenLifetimeMetrics bool
+
+	// CoalesceGets makes concurrent Get calls of the same disk share a
+	// single request: a Get made while another Get of the same project,
+	// zone and disk is in flight waits for that request, and returns a copy
+	// of its disk or its error, instead of sending its own. Nothing is
+	// cached once the request completes. Calls only share requests made
+	// with the same request reason, and the call options of a call that
+	// shares the request of another call are not applied. If the call that
+	// sent the request fails because its context is done, the calls that
+	// wait for it send their own request.
+	//
+	// Defaults to false, which sends a request for each call.
+	CoalesceGets bool
+
+	// Transport is the transport of the clients created with
+	// NewDisksClientWithConfig. NewDisksRESTClientWithConfig always creates
+	// REST clients, and ignores it.
+	//
+	// Defaults to TransportREST.
+	Transport Transport
+
+	// clock, if set, replaces the clock of the system for the pauses and
+	// timings of the client. It is set by tests.
+	clock clock
+}
+
+// Transport is a transport a DisksClient sends its requests with.
+type Transport int
+
+const (
+	// TransportREST sends requests as JSON over HTTP/1.1. It is the default
+	// transport.
+	TransportREST Transport = iota
+
+	// TransportGRPC sends requests with gRPC. The Compute API is only served
+	// over REST for now, so it is reserved: creating a client with it fails
+	// with an error matching ErrTransportUnavailable.
+	TransportGRPC
+)
+
+func (t Transport) String() string {
+	switch t {
+	case TransportREST:
+		return "REST"
+	case TransportGRPC:
+		return "gRPC"
+	}
+	return fmt.Sprintf("Transport(%d)", int(t))
+}
+
+// requestReasonHeader is the header that carries the reason of a request for
+// audit logging.
+const requestReasonHeader = "X-Goog-Request-Reason"
+
+// maxRequestReasonLen is the maximum length of a request reason.
+const maxRequestReasonLen = 512
+
+// requestReasonKey is the context key of the reason set by WithRequestReason.
+type requestReasonKey struct{}
+
+// WithRequestReason returns a copy of ctx that makes the DisksClient calls
+// made with it send reason as their justification for audit logging, in
+// place of DisksClientConfig.RequestReason. A call made with an invalid
+// reason, which is longer than 512 characters or holds characters other
+// than printable ASCII, fails without being sent.
+func WithRequestReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, requestReasonKey{}, reason)
+}
+
+// validateRequestReason returns an error if reason cannot be sent as the
+// reason of a request.
+func validateRequestReason(reason string) error {
+	if len(reason) > maxRequestReasonLen {
+		return fmt.Errorf("compute: request reason is %d characters long, at most %d are allowed", len(reason), maxRequestReasonLen)
+	}
+	for _, r := range reason {
+		if r < ' ' || r > '~' {
+			return fmt.Errorf("compute: request reason %q holds a character that is not printable ASCII", reason)
+		}
+	}
+	return nil
+}
+
+// requestReason returns the reason to send with a request made with ctx, or
+// "" if there is none.
+func (cfg DisksClientConfig) requestReason(ctx context.Context) (string, error) {
+	reason, ok := ctx.Value(requestReasonKey{}).(string)
+	if !ok {
+		return cfg.RequestReason, nil
+	}
+	if err := validateRequestReason(reason); err != nil {
+		return "", err
+	}
+	return reason, nil
+}
+
+// maxReleaseIDLen is the maximum length of DisksClientConfig.ReleaseID.
+const maxReleaseIDLen = 128
+
+// defaultBasePath is the prefix of the paths of Compute API requests, which
+// DisksClientConfig.BasePath replaces.
+const defaultBasePath = "/compute/v1"
+
+// validateBasePath returns an error if p is not empty and not an absolute
+// path.
+func validateBasePath(p string) error {
+	if p == "" {
+		return nil
+	}
+	if !strings.HasPrefix(p, "/") || strings.ContainsAny(p, "?#") {
+		return fmt.Errorf("compute: BasePath %q is not an absolute path", p)
+	}
+	return nil
+}
+
+// validateReleaseID returns an error if id cannot be sent as the version of an
+// entry of the `x-goog-api-client` header, whose entries are separated by
+// spaces and whose names are separated from their versions by slashes.
+func validateReleaseID(id string) error {
+	if len(id) > maxReleaseIDLen {
+		return fmt.Errorf("compute: ReleaseID is %d characters long, at most %d are allowed", len(id), maxReleaseIDLen)
+	}
+	for _, r := range id {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', strings.ContainsRune("._-+", r):
+		default:
+			return fmt.Errorf("compute: ReleaseID %q has the invalid character %q", id, r)
+		}
+	}
+	return nil
+}
+
+// RetryBudget configures a token bucket that limits the rate of retries.
+// Each retry takes a token from the bucket, which holds up to Burst tokens
+// and is refilled at Rate tokens per second. The bucket starts full.
+type RetryBudget struct {
+	// Rate is the number of retries per second allowed on average. It must
+	// be positive.
+	Rate float64
+
+	// Burst is the maximum number of retries allowed in a burst. It must be
+	// at least 1.
+	Burst int
+}
+
+// validate returns an error if b is not a valid retry budget. A nil budget is
+// valid.
+func (b *RetryBudget) validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.Rate <= 0 {
+		return fmt.Errorf("compute: RetryBudget.Rate must be positive, got %v", b.Rate)
+	}
+	if b.Burst < 1 {
+		return fmt.Errorf("compute: RetryBudget.Burst must be at least 1, got %d", b.Burst)
+	}
+	return nil
+}
+
+// disksMethod describes the retry behavior of a disks method.
+type disksMethod struct {
+	// rpc is the name of the method in the Compute API.
+	rpc string
+	// idempotent reports whether the method is safe to retry.
+	idempotent bool
+	// requestID reports whether the method accepts a RequestId.
+	requestID bool
+}
+
+// disksMethods describes each DisksClient method, keyed by its Go name.
+var disksMethods = map[string]disksMethod{
+	"AddResourcePolicies":    {rpc: "compute.disks.addResourcePolicies", requestID: true},
+	"AggregatedList":         {rpc: "compute.disks.aggregatedList", idempotent: true},
+	"CreateSnapshot":         {rpc: "compute.disks.createSnapshot", requestID: true},
+	"Delete":                 {rpc: "compute.disks.delete", requestID: true},
+	"Get":                    {rpc: "compute.disks.get", idempotent: true},
+	"GetIamPolicy":           {rpc: "compute.disks.getIamPolicy", idempotent: true},
+	"Insert":                 {rpc: "compute.disks.insert", requestID: true},
+	"List":                   {rpc: "compute.disks.list", idempotent: true},
+	"RemoveResourcePolicies": {rpc: "compute.disks.removeResourcePolicies", requestID: true},
+	"Resize":                 {rpc: "compute.disks.resize", requestID: true},
+	"SetIamPolicy":           {rpc: "compute.disks.setIamPolicy"},
+	"SetLabels":              {rpc: "compute.disks.setLabels", requestID: true},
+	"TestIamPermissions":     {rpc: "compute.disks.testIamPermissions", idempotent: true},
+}
+
+// zoneOperationsGet describes the zone operations method the disks client
+// polls to wait for its operations.
+var zoneOperationsGet = disksMethod{rpc: "compute.zoneOperations.get", idempotent: true}
+
+// disksMethodByRPC returns the description of the disks method with the given
+// RPC name.
+func disksMethodByRPC(rpc string) disksMethod {
+	if rpc == zoneOperationsGet.rpc {
+		return zoneOperationsGet
+	}
+	for _, m := range disksMethods {
+		if m.rpc == rpc {
+			return m
+		}
+	}
+	return disksMethod{rpc: rpc}
+}
+
+// retryMutatingMethods validates RetryMutatingMethods and returns the set of
+// RPC names it enables.
+func (cfg DisksClientConfig) retryMutatingMethods() (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, name := range cfg.RetryMutatingMethods {
+		m, ok := disksMethods[name]
+		if !ok {
+			return nil, fmt.Errorf("compute: unknown disks method %q in RetryMutatingMethods", name)
+		}
+		enabled[m.rpc] = true
+	}
+	return enabled, nil
+}
+
+// forMethod returns the call options field for the DisksClient method with
+// the given Go name.
+func (o *DisksCallOptions) forMethod(name string) *[]gax.CallOption {
+	switch name {
+	case "AddResourcePolicies":
+		return &o.AddResourcePolicies
+	case "AggregatedList":
+		return &o.AggregatedList
+	case "CreateSnapshot":
+		return &o.CreateSnapshot
+	case "Delete":
+		return &o.Delete
+	case "Get":
+		return &o.Get
+	case "GetIamPolicy":
+		return &o.GetIamPolicy
+	case "Insert":
+		return &o.Insert
+	case "List":
+		return &o.List
+	case "RemoveResourcePolicies":
+		return &o.RemoveResourcePolicies
+	case "Resize":
+		return &o.Resize
+	case "SetIamPolicy":
+		return &o.SetIamPolicy
+	case "SetLabels":
+		return &o.SetLabels
+	case "TestIamPermissions":
+		return &o.TestIamPermissions
+	}
+	return nil
+}
+
+// newRequestID returns a random version 4 UUID for use as a RequestId.
+func newRequestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// diskOrderByValues are the orderBy values Compute accepts when listing
+// disks. Compute only supports ordering by name, or by creation time in
+// descending order.
+var diskOrderByValues = []string{
+	"name",
+	"creationTimestamp desc",
+}
+
+// validateDiskOrderBy returns an error if orderBy is not a value Compute
+// accepts when listing disks. An empty orderBy is always valid.
+func validateDiskOrderBy(orderBy string) error {
+	if orderBy == "" {
+		return nil
+	}
+	normalized := strings.Join(strings.Fields(orderBy), " ")
+	for _, v := range diskOrderByValues {
+		if normalized == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("compute: unsupported orderBy %q for disks; supported values are %q", orderBy, diskOrderByValues)
+}
+
+// resolveEndpoint returns the client option that sets the default endpoint
+// chosen by EndpointResolver, or nil if the static default is to be used.
+// opts are the options the client is created with.
+func (cfg DisksClientConfig) resolveEndpoint(ctx context.Context, opts []option.ClientOption) option.ClientOption {
+	if cfg.EndpointResolver == nil {
+		return nil
+	}
+	creds, err := transport.Creds(ctx, opts...)
+	if err != nil {
+		cfg.logf("compute: resolving endpoint: finding credentials: %v", err)
+		return nil
+	}
+	if creds.ProjectID == "" {
+		return nil
+	}
+	endpoint, err := cfg.EndpointResolver(ctx, creds.ProjectID)
+	if err != nil {
+		cfg.logf("compute: resolving endpoint for project %q: %v", creds.ProjectID, err)
+		return nil
+	}
+	if endpoint == "" {
+		return nil
+	}
+	return internaloption.WithDefaultEndpoint(endpoint)
+}
+
+// logf writes a diagnostic message to Logger, if it is set.
+func (cfg DisksClientConfig) logf(format string, v ...interface{}) {
+	if cfg.Logger != nil {
+		cfg.Logger.Printf(format, v...)
+	}
+}
+
+// systemClock returns the clock of the client: clock if it is set, or the
+// clock of the system.
+func (cfg DisksClientConfig) systemClock() clock {
+	if cfg.clock != nil {
+		return cfg.clock
+	}
+	return realClock{}
+}
+
+// baseTransport returns the base HTTP transport with the configured
+// DialTimeout and MinTLSVersion, or nil if the default transport is to be
+// used. Apart from them, it has the settings of http.DefaultTransport.
+func (cfg DisksClientConfig) baseTransport() http.RoundTripper {
+	if cfg.DialTimeout <= 0 && cfg.MinTLSVersion == 0 {
+		return nil
+	}
+	dialTimeout := cfg.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 30 * time.Second
+	}
+	trans := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if cfg.MinTLSVersion != 0 {
+		trans.TLSClientConfig = &tls.Config{MinVersion: cfg.MinTLSVersion}
+		// A transport with a TLSClientConfig only uses HTTP/2 if asked to,
+		// like http.DefaultTransport does.
+		trans.ForceAttemptHTTP2 = true
+	}
+	return trans
+}
+
+// validateGzipRequestThreshold returns an error if n is negative.
+func validateGzipRequestThreshold(n int) error {
+	if n < 0 {
+		return fmt.Errorf("compute: GzipRequestThreshold must not be negative, got %d", n)
+	}
+	return nil
+}
+
+// validateMinTLSVersion returns an error if v is not a TLS version, or 0.
+func validateMinTLSVersion(v uint16) error {
+	switch v {
+	case 0, tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		return nil
+	}
+	return fmt.Errorf("compute: MinTLSVersion %#04x is not a TLS version", v)
+}
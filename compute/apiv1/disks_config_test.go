@@ -0,0 +1,740 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	gax "github.com/googleapis/gax-go/v2"
+	"go.opencensus.io/stats/view"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestListPrettyPrint(t *testing.T) {
+	for _, prettyPrint := range []bool{false, true} {
+		var got []string
+		c, teardown := newFakeDisksClient(t, DisksClientConfig{PrettyPrint: prettyPrint}, func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.URL.Query().Get("prettyPrint"))
+			if strings.HasSuffix(r.URL.Path, "/aggregated/disks") {
+				w.Write([]byte(`{"items":{"zones/z":{"disks":[{"name":"d","sizeGb":"10"}]}}}`))
+				return
+			}
+			w.Write([]byte(`{"items":[{"name":"d","sizeGb":"10"}]}`))
+		})
+
+		ctx := context.Background()
+		d, err := c.List(ctx, &computepb.ListDisksRequest{Project: "p", Zone: "z"}).Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.GetName() != "d" || d.GetSizeGb() != 10 {
+			t.Errorf("got disk %v from a compact response", d)
+		}
+		if _, err := c.AggregatedList(ctx, &computepb.AggregatedListDisksRequest{Project: "p"}).Next(); err != nil {
+			t.Fatal(err)
+		}
+		teardown()
+
+		want := []string{"false", "false"}
+		if prettyPrint {
+			want = []string{"", ""}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("PrettyPrint %v: prettyPrint parameters mismatch (-want +got):\n%s", prettyPrint, diff)
+		}
+	}
+}
+
+func TestListValidateOrderBy(t *testing.T) {
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{ValidateOrderBy: true}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	ctx := context.Background()
+	for _, test := range []struct {
+		orderBy string
+		wantErr bool
+	}{
+		{"", false},
+		{"name", false},
+		{"creationTimestamp desc", false},
+		{"creationTimestamp  desc", false},
+		{"creationTimestamp", true},
+		{"sizeGb desc", true},
+	} {
+		it := c.List(ctx, &computepb.ListDisksRequest{Project: "p", Zone: "z", OrderBy: proto.String(test.orderBy)})
+		_, err := it.Next()
+		if gotErr := err != iterator.Done; gotErr != test.wantErr {
+			t.Errorf("orderBy %q: got err %v, want error: %v", test.orderBy, err, test.wantErr)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("server received %d calls, want 4", calls)
+	}
+}
+
+func TestAdaptivePageSize(t *testing.T) {
+	var sizes []string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{AdaptivePageSize: true}, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		sizes = append(sizes, q.Get("maxResults"))
+		n, err := strconv.Atoi(q.Get("maxResults"))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		items := make([]string, n)
+		for i := range items {
+			items[i] = `{"name": "d"}`
+		}
+		next := ""
+		if len(sizes) < 4 {
+			next = fmt.Sprintf(`, "nextPageToken": "%d"`, len(sizes))
+		}
+		fmt.Fprintf(w, `{"items": [%s]%s}`, strings.Join(items, ","), next)
+	})
+	defer teardown()
+
+	it := c.List(context.Background(), &computepb.ListDisksRequest{Project: "p", Zone: "z"})
+	for {
+		if _, err := it.Next(); err == iterator.Done {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"100", "200", "400", "500"}
+	if diff := cmp.Diff(want, sizes); diff != "" {
+		t.Errorf("page sizes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPageSizerShrinks(t *testing.T) {
+	s := newPageSizer()
+	for i := 0; i < 5; i++ {
+		s.observe(int(s.size), 5*time.Second)
+	}
+	if s.size != minPageSize {
+		t.Errorf("size after slow pages = %d, want %d", s.size, minPageSize)
+	}
+	s.observe(int(s.size)-1, time.Millisecond)
+	if s.size != minPageSize {
+		t.Errorf("size after a fast partial page = %d, want %d", s.size, minPageSize)
+	}
+}
+
+func TestCheckDeadlines(t *testing.T) {
+	if err := EnableMissingDeadlineCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableMissingDeadlineCountView()
+
+	var buf bytes.Buffer
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{CheckDeadlines: true, Logger: log.New(&buf, "", 0)}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := c.Get(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("got log output %q for a call with a deadline, want none", buf.String())
+	}
+	if _, err := c.Get(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if want := "method=compute.disks.get has no context deadline"; !strings.Contains(buf.String(), want) {
+		t.Errorf("log output %q does not contain %q", buf.String(), want)
+	}
+
+	rows, err := view.RetrieveData(MissingDeadlineCountView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Data.(*view.CountData).Value != 1 {
+		t.Errorf("got rows %v, want a count of 1", rows)
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	var paths []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.Contains(r.URL.Path, "/operations/") {
+			w.Write([]byte(`{"name": "op", "status": "DONE"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer svr.Close()
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		endpoint, basePath, want string
+	}{
+		{"", "", "/compute/v1/projects/p/zones/z"},
+		{"", "/emulator/v1", "/emulator/v1/projects/p/zones/z"},
+		{"", "/", "/projects/p/zones/z"},
+		{"/mock", "/v1/", "/mock/v1/projects/p/zones/z"},
+	} {
+		c, err := NewDisksRESTClientWithConfig(ctx, DisksClientConfig{BasePath: test.basePath}, option.WithEndpoint(svr.URL+test.endpoint), option.WithoutAuthentication())
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = nil
+		if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+			t.Fatal(err)
+		}
+		op := &Operation{proto: &computepb.Operation{
+			Name:     proto.String("op"),
+			SelfLink: proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/operations/op"),
+		}}
+		if _, err := c.WaitForOperation(ctx, op, fastPoll); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := c.RawRequest(ctx, "GET", "/compute/v1/projects/p/zones/z/disks/d", nil); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{test.want + "/disks/d", test.want + "/operations/op", test.want + "/disks/d"}
+		if diff := cmp.Diff(want, paths); diff != "" {
+			t.Errorf("endpoint path %q, BasePath %q: paths mismatch (-want +got):\n%s", test.endpoint, test.basePath, diff)
+		}
+		c.Close()
+	}
+}
+
+func TestGoogleClientInfo(t *testing.T) {
+	var header string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{ClientInfo: []string{"myapp", "1.2.0"}, RESTVersion: "0.63.0"}, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Goog-Api-Client")
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GoogleClientInfo(); got != header {
+		t.Errorf("GoogleClientInfo() = %q, but the client sent %q", got, header)
+	}
+	for _, want := range []string{"gl-go/", "myapp/1.2.0", "gapic/" + versionClient, "rest/0.63.0"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("x-goog-api-client %q does not contain %q", header, want)
+		}
+	}
+}
+
+func TestReleaseID(t *testing.T) {
+	var header string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{ReleaseID: "v1.4.2+build.7"}, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Goog-Api-Client")
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(header, " release/v1.4.2+build.7 ") {
+		t.Errorf("x-goog-api-client %q does not contain the release", header)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer svr.Close()
+	c, err := NewDisksRESTClient(context.Background(), option.WithEndpoint(svr.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	hc := c.HTTPClient()
+	if hc != c.internalClient.(*disksRESTClient).httpClient {
+		t.Fatal("HTTPClient does not return the client requests are sent with")
+	}
+	rsp, err := hc.Get(svr.URL + "/compute/v1/projects/p/zones/z/diskTypes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", rsp.StatusCode)
+	}
+}
+
+func TestEndpointResolver(t *testing.T) {
+	creds := &google.Credentials{
+		ProjectID:   "my-project",
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}),
+	}
+	for _, test := range []struct {
+		desc     string
+		resolved string
+		err      error
+		opts     []option.ClientOption
+		want     string
+	}{
+		{desc: "resolved", resolved: "https://compute.example.com", want: "https://compute.example.com"},
+		{desc: "empty", resolved: "", want: "https://compute.googleapis.com"},
+		{desc: "error", err: errors.New("no location"), want: "https://compute.googleapis.com"},
+		{
+			desc:     "explicit endpoint",
+			resolved: "https://compute.example.com",
+			opts:     []option.ClientOption{option.WithEndpoint("https://override.example.com")},
+			want:     "https://override.example.com",
+		},
+	} {
+		var gotProject string
+		config := DisksClientConfig{
+			EndpointResolver: func(ctx context.Context, projectID string) (string, error) {
+				gotProject = projectID
+				return test.resolved, test.err
+			},
+		}
+		opts := append([]option.ClientOption{option.WithCredentials(creds)}, test.opts...)
+		c, err := NewDisksRESTClientWithConfig(context.Background(), config, opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got := c.internalClient.(*disksRESTClient).endpoint; got != test.want {
+			t.Errorf("%s: endpoint = %q, want %q", test.desc, got, test.want)
+		}
+		if gotProject != "my-project" {
+			t.Errorf("%s: resolver called with project %q, want %q", test.desc, gotProject, "my-project")
+		}
+		c.Close()
+	}
+}
+
+func TestDefaultProjectZone(t *testing.T) {
+	var gotPaths []string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{DefaultProject: "dp", DefaultZone: "dz"}, func(w http.ResponseWriter, r *http.Request) {
+		gotPaths = append(gotPaths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	req := &computepb.GetDiskRequest{Disk: "d"}
+	if _, err := c.Get(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Project != "" || req.Zone != "" {
+		t.Errorf("request was modified: %v", req)
+	}
+	if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.List(ctx, &computepb.ListDisksRequest{Zone: "z"}).Next(); err != iterator.Done {
+		t.Fatalf("got %v, want iterator.Done", err)
+	}
+	if _, err := c.AggregatedList(ctx, nil).Next(); err != iterator.Done {
+		t.Fatalf("got %v, want iterator.Done", err)
+	}
+	want := []string{
+		"/compute/v1/projects/dp/zones/dz/disks/d",
+		"/compute/v1/projects/p/zones/dz/disks/d",
+		"/compute/v1/projects/dp/zones/z/disks",
+		"/compute/v1/projects/dp/aggregated/disks",
+	}
+	if diff := cmp.Diff(want, gotPaths); diff != "" {
+		t.Errorf("paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultProjectZoneMissing(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{DefaultProject: "dp"}, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	if _, err := c.Delete(ctx, &computepb.DeleteDiskRequest{Disk: "d"}); err == nil || !strings.Contains(err.Error(), "DefaultZone") {
+		t.Errorf("Delete: got %v, want error naming DefaultZone", err)
+	}
+	if _, err := c.List(ctx, &computepb.ListDisksRequest{}).Next(); err == nil || !strings.Contains(err.Error(), "DefaultZone") {
+		t.Errorf("List: got %v, want error naming DefaultZone", err)
+	}
+}
+
+func TestContentSHA256(t *testing.T) {
+	for _, threshold := range []int{0, 1} {
+		headers := make(map[string]string)
+		var encoding string
+		c, teardown := newFakeDisksClient(t, DisksClientConfig{ContentSHA256: true, GzipRequestThreshold: threshold}, func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			got := r.Header.Get("X-Goog-Content-SHA256")
+			if got != "" {
+				sum := sha256.Sum256(body)
+				if want := hex.EncodeToString(sum[:]); got != want {
+					t.Errorf("%s %s: checksum %q, want %q", r.Method, r.URL.Path, got, want)
+				}
+			}
+			if r.Method == "POST" {
+				encoding = r.Header.Get("Content-Encoding")
+			}
+			headers[r.Method] = got
+			w.Write([]byte(`{}`))
+		})
+		ctx := context.Background()
+
+		if _, err := c.SetLabels(ctx, &computepb.SetLabelsDiskRequest{
+			Project: "p", Zone: "z", Resource: "d",
+			ZoneSetLabelsRequestResource: &computepb.ZoneSetLabelsRequest{Labels: map[string]string{"env": "prod"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Delete(ctx, &computepb.DeleteDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+			t.Fatal(err)
+		}
+		teardown()
+		if wantGzip := threshold > 0; (encoding == "gzip") != wantGzip {
+			t.Errorf("GzipRequestThreshold %d: got Content-Encoding %q", threshold, encoding)
+		}
+		if headers["POST"] == "" {
+			t.Errorf("GzipRequestThreshold %d: SetLabels sent no checksum", threshold)
+		}
+		if headers["DELETE"] != "" || headers["GET"] != "" {
+			t.Errorf("GzipRequestThreshold %d: methods without a body sent checksums %q, want none", threshold, headers)
+		}
+	}
+}
+
+func TestGzipRequestThreshold(t *testing.T) {
+	var encodings []string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{GzipRequestThreshold: 100}, func(w http.ResponseWriter, r *http.Request) {
+		enc := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, enc)
+		body := r.Body
+		if enc == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = zr
+		}
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if r.Method == "POST" && !json.Valid(b) {
+			t.Errorf("body %q is not JSON", b)
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	for _, value := range []string{"small", strings.Repeat("large", 50)} {
+		if _, err := c.SetLabels(ctx, &computepb.SetLabelsDiskRequest{
+			Project: "p", Zone: "z", Resource: "d",
+			ZoneSetLabelsRequestResource: &computepb.ZoneSetLabelsRequest{Labels: map[string]string{"env": value}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "gzip", ""}; !cmp.Equal(encodings, want) {
+		t.Errorf("got encodings %q, want %q", encodings, want)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{DialTimeout: time.Second, RequestTimeout: 50 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/compute/v1/projects/p/zones/z/disks/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		w.Write([]byte(`{"name": "d"}`))
+	})
+	defer teardown()
+	noRetry := gax.WithRetry(func() gax.Retryer { return nil })
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}, noRetry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "slow"}, noRetry); err == nil {
+		t.Error("got nil error for a request slower than RequestTimeout, want error")
+	}
+}
+
+func TestMinTLSVersion(t *testing.T) {
+	svr := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "d"}`))
+	}))
+	svr.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	svr.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	svr.StartTLS()
+	defer svr.Close()
+	ctx := context.Background()
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	noRetry := gax.WithRetry(func() gax.Retryer { return nil })
+
+	c, err := NewDisksRESTClientWithConfig(ctx, DisksClientConfig{MinTLSVersion: tls.VersionTLS13},
+		option.WithEndpoint(svr.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Get(ctx, req, noRetry); err == nil || !strings.Contains(err.Error(), "protocol version") {
+		t.Errorf("got %v, want a TLS version error from a TLS 1.2 server", err)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var gotScope string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		// The assertion is a JWT whose claims hold the requested scopes.
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("invalid assertion %q", r.FormValue("assertion"))
+			return
+		}
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var c struct {
+			Scope string `json:"scope"`
+		}
+		if err := json.Unmarshal(claims, &c); err != nil {
+			t.Error(err)
+			return
+		}
+		gotScope = c.Scope
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer ts.Close()
+	creds, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "sa@p.iam.gserviceaccount.com",
+		"private_key":  string(pemKey),
+		"token_uri":    ts.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		desc string
+		opts []option.ClientOption
+		want []string
+	}{
+		{"default", nil, DefaultAuthScopes()},
+		{
+			"explicit",
+			[]option.ClientOption{option.WithScopes("https://www.googleapis.com/auth/compute.readonly")},
+			[]string{"https://www.googleapis.com/auth/compute.readonly"},
+		},
+	} {
+		gotScope = ""
+		opts := append([]option.ClientOption{option.WithEndpoint(ts.URL), option.WithCredentialsJSON(creds)}, test.opts...)
+		c, err := NewDisksRESTClient(context.Background(), opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		c.Close()
+		if got, want := gotScope, strings.Join(test.want, " "); got != want {
+			t.Errorf("%s: requested scopes %q, want %q", test.desc, got, want)
+		}
+	}
+}
+
+func TestRejectUnknownFields(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "d", "someNewField": true}`))
+	}
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+
+	for _, reject := range []bool{false, true} {
+		c, teardown := newFakeDisksClient(t, DisksClientConfig{RejectUnknownFields: reject}, handler)
+		d, err := c.Get(context.Background(), req)
+		teardown()
+		if reject {
+			if err == nil || !strings.Contains(err.Error(), "someNewField") {
+				t.Errorf("RejectUnknownFields: got %v, want an error about the unknown field", err)
+			}
+		} else if err != nil || d.GetName() != "d" {
+			t.Errorf("got %v, %v, want the disk with the unknown field ignored", d, err)
+		}
+	}
+}
+
+func TestRequestReason(t *testing.T) {
+	var got []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Goog-Request-Reason"))
+		w.Write([]byte(`{"name": "d"}`))
+	}
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{RequestReason: "ticket-1"}, handler)
+	defer teardown()
+
+	ctx := context.Background()
+	for _, ctx := range []context.Context{ctx, WithRequestReason(ctx, "ticket-2"), WithRequestReason(ctx, "")} {
+		if _, err := c.Get(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if diff := cmp.Diff([]string{"ticket-1", "ticket-2", ""}, got); diff != "" {
+		t.Errorf("reasons mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, reason := range []string{strings.Repeat("x", 513), "a\r\nX-Injected: 1", "café"} {
+		if _, err := c.Get(WithRequestReason(ctx, reason), req); err == nil {
+			t.Errorf("%q: got no error for an invalid reason", reason)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("got %d requests, want invalid reasons not to be sent", len(got))
+	}
+}
+
+func TestNewDisksClientTransport(t *testing.T) {
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "d"}`))
+	}))
+	defer svr.Close()
+	ctx := context.Background()
+	opts := []option.ClientOption{option.WithEndpoint(svr.URL), option.WithoutAuthentication()}
+
+	for _, config := range []DisksClientConfig{{}, {Transport: TransportREST}} {
+		c, err := NewDisksClientWithConfig(ctx, config, opts...)
+		if err != nil {
+			t.Fatalf("%v: %v", config.Transport, err)
+		}
+		if _, ok := c.internalClient.(*disksRESTClient); !ok {
+			t.Errorf("%v: got a %T, want a REST client", config.Transport, c.internalClient)
+		}
+		d, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"})
+		if err != nil {
+			t.Fatalf("%v: %v", config.Transport, err)
+		}
+		if d.GetName() != "d" {
+			t.Errorf("%v: got disk %q, want d", config.Transport, d.GetName())
+		}
+		c.Close()
+	}
+
+	for _, tr := range []Transport{TransportGRPC, Transport(42)} {
+		if _, err := NewDisksClientWithConfig(ctx, DisksClientConfig{Transport: tr}, opts...); !xerrors.Is(err, ErrTransportUnavailable) {
+			t.Errorf("%v: got %v, want ErrTransportUnavailable", tr, err)
+		}
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	withHTTPClient := []option.ClientOption{option.WithHTTPClient(http.DefaultClient)}
+	for _, test := range []struct {
+		desc   string
+		config DisksClientConfig
+		opts   []option.ClientOption
+	}{
+		{desc: "unknown RetryMutatingMethods", config: DisksClientConfig{RetryMutatingMethods: []string{"Create"}}},
+		{desc: "RetryBudget without rate", config: DisksClientConfig{RetryBudget: &RetryBudget{Rate: 0, Burst: 1}}},
+		{desc: "RetryBudget without burst", config: DisksClientConfig{RetryBudget: &RetryBudget{Rate: 1, Burst: 0}}},
+		{desc: "relative BasePath", config: DisksClientConfig{BasePath: "compute/v1"}},
+		{desc: "BasePath with a query", config: DisksClientConfig{BasePath: "/v1?alt=json"}},
+		{desc: "ReleaseID with a space", config: DisksClientConfig{ReleaseID: "v1 2"}},
+		{desc: "ReleaseID with a slash", config: DisksClientConfig{ReleaseID: "a/b"}},
+		{desc: "ReleaseID with a line break", config: DisksClientConfig{ReleaseID: "v1\r\nX-Injected: 1"}},
+		{desc: "non-ASCII ReleaseID", config: DisksClientConfig{ReleaseID: "é"}},
+		{desc: "long ReleaseID", config: DisksClientConfig{ReleaseID: strings.Repeat("x", 129)}},
+		{desc: "long RequestReason", config: DisksClientConfig{RequestReason: strings.Repeat("x", 513)}},
+		{desc: "negative GzipRequestThreshold", config: DisksClientConfig{GzipRequestThreshold: -1}},
+		{desc: "DialTimeout with option.WithHTTPClient", config: DisksClientConfig{DialTimeout: time.Second}, opts: withHTTPClient},
+		{desc: "MinTLSVersion SSL 3.0", config: DisksClientConfig{MinTLSVersion: tls.VersionSSL30}},
+		{desc: "unknown MinTLSVersion", config: DisksClientConfig{MinTLSVersion: 0x0305}},
+		{
+			desc:   "DialTimeout with the mTLS endpoint",
+			config: DisksClientConfig{DialTimeout: time.Second},
+			opts:   []option.ClientOption{option.WithEndpoint(defaultDisksMTLSEndpoint)},
+		},
+		{
+			desc:   "MinTLSVersion with the mTLS endpoint",
+			config: DisksClientConfig{MinTLSVersion: tls.VersionTLS12},
+			opts:   []option.ClientOption{option.WithEndpoint(defaultDisksMTLSEndpoint)},
+		},
+		{desc: "MinTLSVersion with option.WithHTTPClient", config: DisksClientConfig{MinTLSVersion: tls.VersionTLS12}, opts: withHTTPClient},
+	} {
+		opts := append([]option.ClientOption{option.WithoutAuthentication()}, test.opts...)
+		if _, err := NewDisksRESTClientWithConfig(context.Background(), test.config, opts...); err == nil {
+			t.Errorf("%s: got nil error, want error", test.desc)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/iterator"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TotalSizeGB returns the combined size in GB of all persistent disks in the
+// project, together with a per-scope breakdown keyed by the aggregated list
+// scope (for example "zones/us-central1-a" or "regions/us-central1").
+//
+// The disks are read with AggregatedList and ReturnPartialSuccess set, so
+// scopes that are temporarily unreachable are left out of the result instead
+// of failing the whole call.
+func (c *DisksClient) TotalSizeGB(ctx context.Context, project string) (int64, map[string]int64, error) {
+	req := &computepb.AggregatedListDisksRequest{
+		Project:              project,
+		ReturnPartialSuccess: proto.Bool(true),
+	}
+	var total int64
+	byScope := make(map[string]int64)
+	it := c.AggregatedList(ctx, req)
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		for _, d := range pair.Value.GetDisks() {
+			total += d.GetSizeGb()
+			byScope[pair.Key] += d.GetSizeGb()
+		}
+	}
+	return total, byScope, nil
+}
+
+// ListCreatedSince returns the disks in the given zone of the project that
+// were created after since. If zone is empty, the disks in all zones and
+// regions of the project are returned.
+//
+// Compute does not record when a disk was last modified, so the creation
+// timestamp is the only time disks can be selected by. The comparison is
+// done on the client: creation timestamps are reported in the time zone of
+// the server, so comparing them as strings in a filter expression does not
+// give reliable results.
+func (c *DisksClient) ListCreatedSince(ctx context.Context, project, zone string, since time.Time) ([]*computepb.Disk, error) {
+	var disks []*computepb.Disk
+	keep := func(d *computepb.Disk) error {
+		created, err := time.Parse(time.RFC3339, d.GetCreationTimestamp())
+		if err != nil {
+			return fmt.Errorf("compute: invalid creation timestamp %q of disk %q: %v", d.GetCreationTimestamp(), d.GetName(), err)
+		}
+		if created.After(since) {
+			disks = append(disks, d)
+		}
+		return nil
+	}
+
+	if zone != "" {
+		it := c.List(ctx, &computepb.ListDisksRequest{Project: project, Zone: zone})
+		for {
+			d, err := it.Next()
+			if err == iterator.Done {
+				return disks, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := keep(d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	it := c.AggregatedList(ctx, &computepb.AggregatedListDisksRequest{Project: project})
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			return disks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range pair.Value.GetDisks() {
+			if err := keep(d); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// InstanceDisksOptions configures ListInstanceDisks. A nil
+// *InstanceDisksOptions, or a zero field, selects the default value.
+type InstanceDisksOptions struct {
+	// AllScopes scans the disks of all zones and regions of the project,
+	// with AggregatedList, so that regional disks attached to the instance
+	// are found as well as the zonal disks of its zone.
+	//
+	// Defaults to false, which only scans the zone of the instance.
+	AllScopes bool
+
+	// Filter restricts the disks scanned, such as to those with a label,
+	// which shortens the scan of a project with many disks.
+	//
+	// Defaults to nil, which scans all disks.
+	Filter *Filter
+
+	// MaxScanned is the largest number of disks scanned, whether they are
+	// attached to the instance or not. When it is reached, the scan stops,
+	// and the disks found so far are returned with ErrScanLimitReached.
+	//
+	// Defaults to 0, which scans all disks.
+	MaxScanned int
+}
+
+// ListInstanceDisks returns the disks attached to the given instance, found
+// by listing the disks and keeping those whose Users include the instance.
+// instance is either the name of an instance in the given zone of the
+// project, or its URL, such as
+// "projects/my-project/zones/us-central1-a/instances/my-instance", whose
+// project and zone take precedence over project and zone.
+//
+// The disks are returned in the order they are listed. Disks attached after
+// they were listed are not returned.
+func (c *DisksClient) ListInstanceDisks(ctx context.Context, project, zone, instance string, opts *InstanceDisksOptions) ([]*computepb.Disk, error) {
+	var o InstanceDisksOptions
+	if opts != nil {
+		o = *opts
+	}
+	name := instance
+	if strings.Contains(instance, "/") {
+		project = linkSegment(instance, "projects")
+		zone = linkSegment(instance, "zones")
+		name = linkSegment(instance, "instances")
+	}
+	if project == "" || zone == "" || name == "" {
+		return nil, fmt.Errorf("compute: cannot find the disks of instance %q in project %q and zone %q", instance, project, zone)
+	}
+	var filter *string
+	if o.Filter != nil {
+		expr, err := o.Filter.Expr()
+		if err != nil {
+			return nil, err
+		}
+		filter = &expr
+	}
+
+	var disks []*computepb.Disk
+	scanned := 0
+	// keep adds d to disks if it is attached to the instance, and reports
+	// whether the scan may go on.
+	keep := func(d *computepb.Disk) bool {
+		for _, u := range d.GetUsers() {
+			if linkSegment(u, "instances") == name && linkSegment(u, "zones") == zone && linkSegment(u, "projects") == project {
+				disks = append(disks, d)
+				break
+			}
+		}
+		scanned++
+		return o.MaxScanned <= 0 || scanned < o.MaxScanned
+	}
+
+	if !o.AllScopes {
+		it := c.List(ctx, &computepb.ListDisksRequest{Project: project, Zone: zone, Filter: filter})
+		for {
+			d, err := it.Next()
+			if err == iterator.Done {
+				return disks, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !keep(d) {
+				return disks, ErrScanLimitReached
+			}
+		}
+	}
+
+	it := c.AggregatedList(ctx, &computepb.AggregatedListDisksRequest{Project: project, Filter: filter})
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			return disks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range pair.Value.GetDisks() {
+			if !keep(d) {
+				return disks, ErrScanLimitReached
+			}
+		}
+	}
+}
+
+// diskOutputOnlyFields are the fields of the Disk resource that are
+// documented as [Output Only] in the Compute API.
+var diskOutputOnlyFields = []protoreflect.Name{
+	"creation_timestamp",
+	"id",
+	"kind",
+	"last_attach_timestamp",
+	"last_detach_timestamp",
+	"region",
+	"satisfies_pzs",
+	"self_link",
+	"source_disk_id",
+	"source_image_id",
+	"source_snapshot_id",
+	"status",
+	"users",
+	"zone",
+}
+
+// ClearDiskOutputOnlyFields clears the output-only fields of d, such as
+// SelfLink, Id and Status, including the Sha256 of its encryption keys, so
+// that a Disk returned by Get can be modified and sent back in a request.
+func ClearDiskOutputOnlyFields(d *computepb.Disk) {
+	m := d.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for _, name := range diskOutputOnlyFields {
+		m.Clear(fields.ByName(name))
+	}
+	for _, key := range []*computepb.CustomerEncryptionKey{
+		d.DiskEncryptionKey,
+		d.SourceImageEncryptionKey,
+		d.SourceSnapshotEncryptionKey,
+	} {
+		if key != nil {
+			key.Sha256 = nil
+		}
+	}
+}
+
+// ListWarning is a warning returned by Compute in a page of a disk listing,
+// such as a notice that a zone is unreachable or that a resource is
+// deprecated. Warnings do not fail a listing.
+type ListWarning struct {
+	// Scope is the aggregated list scope the warning applies to, such as
+	// "zones/us-central1-a", or "" for a warning about the whole page.
+	Scope string
+
+	// Code is the warning code, such as "UNREACHABLE" or
+	// "DEPRECATED_RESOURCE_USED".
+	Code string
+
+	// Message is a human-readable description of the warning.
+	Message string
+}
+
+// Warnings returns the warnings of the page most recently fetched by the
+// iterator, which is the page held in its Response field. Call it after Next
+// returns the last item of each page, for example when PageInfo().Remaining()
+// is 0, to see the warnings of every page.
+func (it *DiskIterator) Warnings() []ListWarning {
+	resp, _ := it.Response.(*computepb.DiskList)
+	return appendListWarning(nil, "", resp.GetWarning())
+}
+
+// Warnings returns the warnings of the page most recently fetched by the
+// iterator, which is the page held in its Response field, including the
+// warnings of each scope, sorted by scope. Call it after Next returns the
+// last item of each page, for example when PageInfo().Remaining() is 0, to
+// see the warnings of every page.
+func (it *DisksScopedListPairIterator) Warnings() []ListWarning {
+	resp, _ := it.Response.(*computepb.DiskAggregatedList)
+	warnings := appendListWarning(nil, "", resp.GetWarning())
+	scopes := make([]string, 0, len(resp.GetItems()))
+	for scope := range resp.GetItems() {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	for _, scope := range scopes {
+		warnings = appendListWarning(warnings, scope, resp.GetItems()[scope].GetWarning())
+	}
+	return warnings
+}
+
+// appendListWarning appends w, if it is non-nil, to warnings as a ListWarning
+// for the given scope.
+func appendListWarning(warnings []ListWarning, scope string, w *computepb.Warning) []ListWarning {
+	if w == nil {
+		return warnings
+	}
+	return append(warnings, ListWarning{Scope: scope, Code: w.GetCode(), Message: w.GetMessage()})
+}
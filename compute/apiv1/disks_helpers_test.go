@@ -0,0 +1,243 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// newFakeDisksClient returns a DisksClient with the given config that sends
+// all requests to a test server backed by handler, and a function that shuts
+// both down.
+func newFakeDisksClient(t *testing.T, config DisksClientConfig, handler http.HandlerFunc) (*DisksClient, func()) {
+	t.Helper()
+	svr := httptest.NewServer(handler)
+	c, err := NewDisksRESTClientWithConfig(context.Background(), config, option.WithEndpoint(svr.URL), option.WithoutAuthentication())
+	if err != nil {
+		svr.Close()
+		t.Fatal(err)
+	}
+	return c, func() {
+		c.Close()
+		svr.Close()
+	}
+}
+
+func TestTotalSizeGB(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("returnPartialSuccess"); got != "true" {
+			t.Errorf("returnPartialSuccess = %q, want true", got)
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			w.Write([]byte(`{"items": {
+				"zones/us-central1-a": {"disks": [{"name": "a", "sizeGb": "10"}, {"name": "b", "sizeGb": "20"}]},
+				"zones/us-central1-b": {"warning": {"code": "NO_RESULTS_ON_PAGE"}}
+			}, "nextPageToken": "next"}`))
+			return
+		}
+		w.Write([]byte(`{"items": {
+			"regions/us-central1": {"disks": [{"name": "c", "sizeGb": "200"}]}
+		}}`))
+	})
+	defer teardown()
+
+	total, byScope, err := c.TotalSizeGB(context.Background(), "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 230 {
+		t.Errorf("total = %d, want 230", total)
+	}
+	want := map[string]int64{
+		"zones/us-central1-a": 30,
+		"regions/us-central1": 200,
+	}
+	if diff := cmp.Diff(want, byScope); diff != "" {
+		t.Errorf("byScope mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestListCreatedSince(t *testing.T) {
+	// Compared as strings, "old" would be newer than since and "new" older.
+	const (
+		oldDisk = `{"name": "old", "creationTimestamp": "2022-01-01T16:00:00.000+02:00"}`
+		newDisk = `{"name": "new", "creationTimestamp": "2022-01-01T08:00:00.000-08:00"}`
+	)
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/aggregated/disks") {
+			w.Write([]byte(`{"items": {
+				"zones/us-central1-a": {"disks": [` + oldDisk + `]},
+				"regions/us-central1": {"disks": [` + newDisk + `]}
+			}}`))
+			return
+		}
+		w.Write([]byte(`{"items": [` + oldDisk + `, ` + newDisk + `]}`))
+	})
+	defer teardown()
+
+	since := time.Date(2022, 1, 1, 15, 0, 0, 0, time.UTC)
+	for _, zone := range []string{"us-central1-a", ""} {
+		disks, err := c.ListCreatedSince(context.Background(), "p", zone, since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, d := range disks {
+			names = append(names, d.GetName())
+		}
+		if diff := cmp.Diff([]string{"new"}, names); diff != "" {
+			t.Errorf("zone %q: disks mismatch (-want +got):\n%s", zone, diff)
+		}
+	}
+}
+
+func TestListInstanceDisks(t *testing.T) {
+	const (
+		boot     = `{"name": "boot", "users": ["https://www.googleapis.com/compute/v1/projects/p/zones/z/instances/vm"]}`
+		data     = `{"name": "data", "users": ["projects/p/zones/z/instances/other", "projects/p/zones/z/instances/vm"]}`
+		other    = `{"name": "other", "users": ["projects/p/zones/z2/instances/vm"]}`
+		free     = `{"name": "free"}`
+		regional = `{"name": "regional", "users": ["projects/p/zones/z/instances/vm"]}`
+	)
+	var filters []string
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		filters = append(filters, r.URL.Query().Get("filter"))
+		if strings.HasSuffix(r.URL.Path, "/aggregated/disks") {
+			w.Write([]byte(`{"items": {
+				"zones/z": {"disks": [` + boot + `, ` + free + `, ` + data + `]},
+				"zones/z2": {"disks": [` + other + `]},
+				"regions/r": {"disks": [` + regional + `]}
+			}}`))
+			return
+		}
+		w.Write([]byte(`{"items": [` + boot + `, ` + free + `, ` + data + `]}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+	names := func(disks []*computepb.Disk) []string {
+		var names []string
+		for _, d := range disks {
+			names = append(names, d.GetName())
+		}
+		return names
+	}
+
+	labeled := Eq("labels.env", "prod")
+	for _, tc := range []struct {
+		project, zone, instance string
+		opts                    *InstanceDisksOptions
+		want                    []string
+		wantErr                 error
+	}{
+		{"p", "z", "vm", nil, []string{"boot", "data"}, nil},
+		{"", "", "projects/p/zones/z/instances/vm", &InstanceDisksOptions{Filter: &labeled}, []string{"boot", "data"}, nil},
+		{"p", "z", "vm", &InstanceDisksOptions{AllScopes: true}, []string{"regional", "boot", "data"}, nil},
+		{"p", "z", "vm", &InstanceDisksOptions{MaxScanned: 2}, []string{"boot"}, ErrScanLimitReached},
+	} {
+		disks, err := c.ListInstanceDisks(ctx, tc.project, tc.zone, tc.instance, tc.opts)
+		if err != tc.wantErr {
+			t.Errorf("%s %+v: got error %v, want %v", tc.instance, tc.opts, err, tc.wantErr)
+		}
+		if diff := cmp.Diff(tc.want, names(disks)); diff != "" {
+			t.Errorf("%s %+v: disks mismatch (-want +got):\n%s", tc.instance, tc.opts, diff)
+		}
+	}
+	if filters[1] != `labels.env = "prod"` {
+		t.Errorf("got filter %q, want the filter of the options", filters[1])
+	}
+
+	if _, err := c.ListInstanceDisks(ctx, "p", "", "vm", nil); err == nil {
+		t.Error("got no error for an instance without a zone")
+	}
+}
+
+func TestClearDiskOutputOnlyFields(t *testing.T) {
+	fields := (&computepb.Disk{}).ProtoReflect().Descriptor().Fields()
+	for _, name := range diskOutputOnlyFields {
+		if fields.ByName(name) == nil {
+			t.Errorf("Disk has no field %q", name)
+		}
+	}
+
+	d := &computepb.Disk{
+		Name:              proto.String("d"),
+		SizeGb:            proto.Int64(10),
+		Id:                proto.Uint64(123),
+		SelfLink:          proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/disks/d"),
+		Status:            proto.String("READY"),
+		Users:             []string{"projects/p/zones/z/instances/i"},
+		Zone:              proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z"),
+		DiskEncryptionKey: &computepb.CustomerEncryptionKey{KmsKeyName: proto.String("k"), Sha256: proto.String("abc")},
+	}
+	ClearDiskOutputOnlyFields(d)
+	want := &computepb.Disk{
+		Name:              proto.String("d"),
+		SizeGb:            proto.Int64(10),
+		DiskEncryptionKey: &computepb.CustomerEncryptionKey{KmsKeyName: proto.String("k")},
+	}
+	if !proto.Equal(d, want) {
+		t.Errorf("got %v, want %v", d, want)
+	}
+}
+
+func TestListWarnings(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/aggregated/disks") {
+			w.Write([]byte(`{
+				"items": {
+					"zones/b": {"warning": {"code": "NO_RESULTS_ON_PAGE", "message": "no disks"}},
+					"zones/a": {"disks": [{"name": "d"}], "warning": {"code": "DEPRECATED_RESOURCE_USED", "message": "deprecated"}}
+				},
+				"warning": {"code": "UNREACHABLE", "message": "zone c unreachable"}
+			}`))
+			return
+		}
+		w.Write([]byte(`{"items": [{"name": "d"}], "warning": {"code": "DEPRECATED_RESOURCE_USED", "message": "deprecated"}}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	it := c.List(ctx, &computepb.ListDisksRequest{Project: "p", Zone: "z"})
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	want := []ListWarning{{Code: "DEPRECATED_RESOURCE_USED", Message: "deprecated"}}
+	if diff := cmp.Diff(want, it.Warnings()); diff != "" {
+		t.Errorf("List warnings mismatch (-want +got):\n%s", diff)
+	}
+
+	ait := c.AggregatedList(ctx, &computepb.AggregatedListDisksRequest{Project: "p"})
+	if _, err := ait.Next(); err != nil {
+		t.Fatal(err)
+	}
+	want = []ListWarning{
+		{Code: "UNREACHABLE", Message: "zone c unreachable"},
+		{Scope: "zones/a", Code: "DEPRECATED_RESOURCE_USED", Message: "deprecated"},
+		{Scope: "zones/b", Code: "NO_RESULTS_ON_PAGE", Message: "no disks"},
+	}
+	if diff := cmp.Diff(want, ait.Warnings()); diff != "" {
+		t.Errorf("AggregatedList warnings mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// PermissionSet is a set of IAM permissions, such as the permissions granted
+// on a resource.
+type PermissionSet map[string]bool
+
+// Has reports whether permission is in the set.
+func (s PermissionSet) Has(permission string) bool {
+	return s[permission]
+}
+
+// TestIamPermissionSet calls TestIamPermissions for the given permissions on
+// the disk resource, and returns the permissions the caller has been granted
+// as a PermissionSet, together with the raw response.
+func (c *DisksClient) TestIamPermissionSet(ctx context.Context, project, zone, resource string, permissions []string, opts ...gax.CallOption) (PermissionSet, *computepb.TestPermissionsResponse, error) {
+	rsp, err := c.TestIamPermissions(ctx, &computepb.TestIamPermissionsDiskRequest{
+		Project:  project,
+		Zone:     zone,
+		Resource: resource,
+		TestPermissionsRequestResource: &computepb.TestPermissionsRequest{
+			Permissions: permissions,
+		},
+	}, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	granted := make(PermissionSet, len(rsp.GetPermissions()))
+	for _, p := range rsp.GetPermissions() {
+		granted[p] = true
+	}
+	return granted, rsp, nil
+}
+
+// defaultIamPolicyConcurrency is the number of concurrent GetIamPolicy
+// requests GetIamPolicies sends by default.
+const defaultIamPolicyConcurrency = 10
+
+// GetIamPolicies fetches the IAM policies of the given disks in a zone of
+// the project, sending at most concurrency GetIamPolicy requests at a time,
+// or 10 if concurrency is less than 1. It returns the policies and the
+// errors of the disks whose policy could not be fetched, both keyed by disk
+// name.
+//
+// If ctx is done before all policies are fetched, no further requests are
+// sent and the remaining disks are reported with the error of ctx.
+func (c *DisksClient) GetIamPolicies(ctx context.Context, project, zone string, disks []string, concurrency int, opts ...gax.CallOption) (map[string]*computepb.Policy, map[string]error) {
+	if concurrency < 1 {
+		concurrency = defaultIamPolicyConcurrency
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		policies = make(map[string]*computepb.Policy)
+		errs     = make(map[string]error)
+		sem      = make(chan struct{}, concurrency)
+	)
+	for _, disk := range disks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			errs[disk] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(disk string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			policy, err := c.GetIamPolicy(ctx, &computepb.GetIamPolicyDiskRequest{
+				Project:  project,
+				Zone:     zone,
+				Resource: disk,
+			}, opts...)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[disk] = err
+				return
+			}
+			policies[disk] = policy
+		}(disk)
+	}
+	wg.Wait()
+	return policies, errs
+}
+
+// UpdateIamPolicy reads the IAM policy of the disk resource, applies mutate
+// to it and writes it back together with the etag of the policy that was
+// read, so that concurrent changes to the policy are not overwritten. If the
+// policy changed in the meantime, it is read and mutated once more before
+// the update is given up. If mutate returns an error, the policy is not
+// written and the error is returned.
+func (c *DisksClient) UpdateIamPolicy(ctx context.Context, project, zone, resource string, mutate func(*computepb.Policy) error) (*computepb.Policy, error) {
+	for attempt := 1; ; attempt++ {
+		policy, err := c.GetIamPolicy(ctx, &computepb.GetIamPolicyDiskRequest{
+			Project:  project,
+			Zone:     zone,
+			Resource: resource,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := mutate(policy); err != nil {
+			return nil, err
+		}
+		updated, err := c.SetIamPolicy(ctx, &computepb.SetIamPolicyDiskRequest{
+			Project:  project,
+			Zone:     zone,
+			Resource: resource,
+			ZoneSetPolicyRequestResource: &computepb.ZoneSetPolicyRequest{
+				Policy: policy,
+				Etag:   policy.Etag,
+			},
+		})
+		var gerr *googleapi.Error
+		if attempt == 1 && xerrors.As(err, &gerr) && gerr.Code == http.StatusPreconditionFailed {
+			continue
+		}
+		return updated, err
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTestIamPermissionSet(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/zones/z/disks/d/testIamPermissions") {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if !strings.Contains(string(body), "compute.disks.delete") {
+			t.Errorf("request body %s does not contain the tested permissions", body)
+		}
+		w.Write([]byte(`{"permissions": ["compute.disks.get"]}`))
+	})
+	defer teardown()
+
+	granted, rsp, err := c.TestIamPermissionSet(context.Background(), "p", "z", "d", []string{"compute.disks.get", "compute.disks.delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !granted.Has("compute.disks.get") {
+		t.Error("compute.disks.get was not granted")
+	}
+	if granted.Has("compute.disks.delete") {
+		t.Error("compute.disks.delete was granted")
+	}
+	if got := rsp.GetPermissions(); len(got) != 1 {
+		t.Errorf("raw response permissions = %v, want 1 permission", got)
+	}
+}
+
+func TestGetIamPolicies(t *testing.T) {
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+		disk := path.Base(path.Dir(r.URL.Path))
+		if disk == "missing" {
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"etag": "` + disk + `"}`))
+	})
+	defer teardown()
+
+	disks := []string{"a", "b", "c", "d", "missing"}
+	policies, errs := c.GetIamPolicies(context.Background(), "p", "z", disks, 2)
+	if len(policies) != 4 {
+		t.Errorf("got %d policies, want 4", len(policies))
+	}
+	for disk, policy := range policies {
+		if policy.GetEtag() != disk {
+			t.Errorf("policy of %q has etag %q", disk, policy.GetEtag())
+		}
+	}
+	if len(errs) != 1 || errs["missing"] == nil {
+		t.Errorf("got errors %v, want an error for disk \"missing\" only", errs)
+	}
+	if peak > 2 {
+		t.Errorf("%d requests were in flight at once, want at most 2", peak)
+	}
+}
+
+func TestGetIamPoliciesCanceled(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policies, errs := c.GetIamPolicies(ctx, "p", "z", []string{"a", "b", "c"}, 1)
+	if len(policies) != 0 {
+		t.Errorf("got %d policies, want none", len(policies))
+	}
+	if len(errs) != 3 {
+		t.Errorf("got %d errors, want 3", len(errs))
+	}
+}
+
+func TestUpdateIamPolicy(t *testing.T) {
+	var etag, sets int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getIamPolicy") {
+			etag++
+			w.Write([]byte(fmt.Sprintf(`{"etag": "e%d", "bindings": [{"role": "roles/viewer", "members": ["user:a@example.com"]}]}`, etag)))
+			return
+		}
+		sets++
+		if sets == 1 {
+			// Simulate a concurrent change after the first read.
+			http.Error(w, `{"error": {"code": 412, "message": "precondition failed"}}`, http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var req computepb.ZoneSetPolicyRequest
+		if err := protojson.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+			return
+		}
+		if got := req.GetEtag(); got != "e2" {
+			t.Errorf("SetIamPolicy etag = %q, want the etag of the second read", got)
+		}
+		rsp, _ := protojson.Marshal(req.GetPolicy())
+		w.Write(rsp)
+	})
+	defer teardown()
+
+	var mutations int
+	policy, err := c.UpdateIamPolicy(context.Background(), "p", "z", "d", func(p *computepb.Policy) error {
+		mutations++
+		p.Bindings = append(p.Bindings, &computepb.Binding{Role: proto.String("roles/owner"), Members: []string{"user:b@example.com"}})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mutations != 2 || sets != 2 {
+		t.Errorf("got %d mutations and %d writes, want 2 of each", mutations, sets)
+	}
+	if got := len(policy.GetBindings()); got != 2 {
+		t.Errorf("got %d bindings, want 2", got)
+	}
+}
+
+func TestUpdateIamPolicyGivesUp(t *testing.T) {
+	var sets int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getIamPolicy") {
+			w.Write([]byte(`{"etag": "e"}`))
+			return
+		}
+		sets++
+		http.Error(w, `{"error": {"code": 412, "message": "precondition failed"}}`, http.StatusPreconditionFailed)
+	})
+	defer teardown()
+
+	_, err := c.UpdateIamPolicy(context.Background(), "p", "z", "d", func(*computepb.Policy) error { return nil })
+	var gerr *googleapi.Error
+	if !xerrors.As(err, &gerr) || gerr.Code != http.StatusPreconditionFailed {
+		t.Errorf("got %v, want a 412 error", err)
+	}
+	if sets != 2 {
+		t.Errorf("got %d writes, want 2", sets)
+	}
+}
+
+func TestUpdateIamPolicyMutateError(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/getIamPolicy") {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"etag": "e"}`))
+	})
+	defer teardown()
+
+	want := errors.New("no change")
+	if _, err := c.UpdateIamPolicy(context.Background(), "p", "z", "d", func(*computepb.Policy) error { return want }); err != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
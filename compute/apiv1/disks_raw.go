@@ -0,0 +1,96 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RawRequest sends a request with the given HTTP method and body to path,
+// such as "/compute/v1/projects/p/zones/z/disks/d?fields=name", relative to
+// the endpoint of the client, and returns the body and status code of the
+// response. It lets tests and debugging tools call parts of the Compute API
+// that DisksClient does not model, with the credentials, transport and
+// headers of the client.
+//
+// Unlike the other methods, it neither retries the request nor records
+// metrics, and a response with an error status is not an error: only
+// failures to send the request or read the response are returned as
+// errors. The body, if any, is sent as JSON. A path starting with
+// "/compute/v1" is rebased on DisksClientConfig.BasePath, if it is set.
+//
+// It is an advanced method, EXPERIMENTAL and subject to change or removal
+// without notice.
+func (c *DisksClient) RawRequest(ctx context.Context, method, path string, body []byte) ([]byte, int, error) {
+	return c.internalClient.doRaw(ctx, method, path, body)
+}
+
+func (c *disksRESTClient) doRaw(ctx context.Context, method, path string, body []byte) ([]byte, int, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Only send the credentials of the client to its endpoint.
+	if ref.IsAbs() || ref.Host != "" || !strings.HasPrefix(ref.Path, "/") {
+		return nil, 0, fmt.Errorf("compute: raw request path %q is not an absolute path", path)
+	}
+	reason, err := c.config.requestReason(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, 0, err
+	}
+	u.Path += ref.Path
+	u.RawQuery = ref.RawQuery
+	c.rebase(u)
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequest(method, u.String(), reqBody)
+	if err != nil {
+		return nil, 0, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	for k, v := range c.xGoogMetadata {
+		httpReq.Header[k] = v
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if reason != "" {
+		httpReq.Header.Set(requestReasonHeader, reason)
+	}
+	httpRsp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpRsp.Body.Close()
+	buf, err := ioutil.ReadAll(httpRsp.Body)
+	if err != nil {
+		return nil, httpRsp.StatusCode, err
+	}
+	return buf, httpRsp.StatusCode, nil
+}
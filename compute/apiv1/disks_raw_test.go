@@ -0,0 +1,63 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestRawRequest(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{RequestReason: "debug"}, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Goog-Api-Client") == "" {
+			t.Error("got no x-goog-api-client header, want the headers of the client")
+		}
+		if got := r.Header.Get("X-Goog-Request-Reason"); got != "debug" {
+			t.Errorf("got request reason %q, want debug", got)
+		}
+		if r.URL.Path == "/compute/v1/projects/p/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		fmt.Fprintf(w, "%s %s?%s %s", r.Method, r.URL.Path, r.URL.RawQuery, body)
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	body, code, err := c.RawRequest(ctx, "POST", "/compute/v1/projects/p/zones/z/disks/d/custom?x=1", []byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `POST /compute/v1/projects/p/zones/z/disks/d/custom?x=1 {"a": 1}`; code != http.StatusOK || string(body) != want {
+		t.Errorf("got %d %q, want 200 %q", code, body, want)
+	}
+
+	body, code, err = c.RawRequest(ctx, "GET", "/compute/v1/projects/p/missing", nil)
+	if err != nil {
+		t.Fatalf("got %v, want an error status not to be an error", err)
+	}
+	if want := "GET /compute/v1/projects/p/missing? "; code != http.StatusNotFound || string(body) != want {
+		t.Errorf("got %d %q, want 404 %q", code, body, want)
+	}
+
+	for _, path := range []string{"https://example.com/compute/v1", "//example.com/compute/v1", "compute/v1"} {
+		if _, _, err := c.RawRequest(ctx, "GET", path, nil); err == nil {
+			t.Errorf("%q: got no error for a path that is not absolute", path)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"go.opencensus.io/tag"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// contentSHA256Header is the header that carries the checksum of the request
+// body when DisksClientConfig.ContentSHA256 is set.
+const contentSHA256Header = "X-Goog-Content-SHA256"
+
+// gzipBody returns b compressed with gzip.
+func gzipBody(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// do sends an HTTP request for the disks method rpc to u, retrying it
+// according to opts, and unmarshals the response into rsp. If body is non-nil
+// it is marshaled as the JSON request body.
+func (c *disksRESTClient) do(ctx context.Context, rpc, method string, u *url.URL, body, rsp proto.Message, opts ...gax.CallOption) error {
+	c.rebase(u)
+	if c.config.CheckDeadlines {
+		c.checkDeadline(ctx, rpc)
+	}
+	if isViewEnabled(CallsByProjectView) {
+		recordStat(ctx, CallsByProject, rpc, 1, tag.Upsert(tagKeyProject, linkSegment(u.Path, "projects")))
+	}
+	if m := disksMethodByRPC(rpc); !m.idempotent {
+		if !c.retryMutating[rpc] {
+			// Never retry a mutating request the caller has not opted in to.
+			opts = append(opts, gax.WithRetry(func() gax.Retryer { return nil }))
+		} else if m.requestID && u.Query().Get("requestId") == "" {
+			id, err := newRequestID()
+			if err != nil {
+				return err
+			}
+			q := u.Query()
+			q.Set("requestId", id)
+			u.RawQuery = q.Encode()
+		}
+	}
+	reason, err := c.config.requestReason(ctx)
+	if err != nil {
+		return err
+	}
+	var jsonReq []byte
+	if body != nil {
+		m := protojson.MarshalOptions{AllowPartial: true}
+		var err error
+		if jsonReq, err = m.Marshal(body); err != nil {
+			return err
+		}
+	}
+	gzipped := false
+	if t := c.config.GzipRequestThreshold; body != nil && t > 0 && len(jsonReq) >= t {
+		if jsonReq, err = gzipBody(jsonReq); err != nil {
+			return err
+		}
+		gzipped = true
+	}
+	// The checksum is that of the bytes sent, compressed or not.
+	var contentSHA256 string
+	if c.config.ContentSHA256 && body != nil && !disksMethodByRPC(rpc).idempotent {
+		sum := sha256.Sum256(jsonReq)
+		contentSHA256 = hex.EncodeToString(sum[:])
+	}
+
+	// The status and header of the response to the last attempt, kept for
+	// IncludeResponseInErrors.
+	var rspStatus string
+	var rspHeader http.Header
+	attempts := 0
+	err = invoke(ctx, c.systemClock(), func(ctx context.Context) (err error) {
+		attempts++
+		rspStatus, rspHeader = "", nil
+		ok, probe := c.breaker.allow(ctx)
+		if !ok {
+			return ErrCircuitOpen
+		}
+		defer func() { c.breaker.done(ctx, probe, err) }()
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(jsonReq)
+		}
+		httpReq, err := http.NewRequest(method, u.String(), reqBody)
+		if err != nil {
+			return err
+		}
+		if c.config.TokenLifetimeMetrics {
+			httpReq = httpReq.WithContext(context.WithValue(ctx, rpcKey{}, rpc))
+		} else {
+			httpReq = httpReq.WithContext(ctx)
+		}
+		// Set the headers
+		for k, v := range c.xGoogMetadata {
+			httpReq.Header[k] = v
+		}
+		httpReq.Header["Content-Type"] = []string{"application/json"}
+		if gzipped {
+			httpReq.Header.Set("Content-Encoding", "gzip")
+		}
+		if contentSHA256 != "" {
+			httpReq.Header.Set(contentSHA256Header, contentSHA256)
+		}
+		if reason != "" {
+			httpReq.Header.Set(requestReasonHeader, reason)
+		}
+
+		inFlight := isViewEnabled(InFlightRequestsView)
+		var tags []tag.Mutator
+		if inFlight {
+			tags = c.locationTags(linkSegment(u.Path, "projects"), linkSegment(u.Path, "zones"))
+			recordStat(ctx, InFlightRequests, rpc, 1, tags...)
+		}
+		httpRsp, err := c.httpClient.Do(httpReq)
+		if inFlight {
+			recordStat(ctx, InFlightRequests, rpc, -1, tags...)
+		}
+		if err != nil {
+			return err
+		}
+		defer httpRsp.Body.Close()
+		if c.config.IncludeResponseInErrors {
+			rspStatus, rspHeader = httpRsp.Status, httpRsp.Header
+		}
+
+		if err = googleapi.CheckResponse(httpRsp); err != nil {
+			return err
+		}
+
+		buf, err := ioutil.ReadAll(httpRsp.Body)
+		if err != nil {
+			return err
+		}
+
+		unm := protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: !c.config.RejectUnknownFields}
+		if isViewEnabled(DecodeDurationView) {
+			start := time.Now()
+			defer func() {
+				recordStat(ctx, DecodeDuration, rpc, int64(time.Since(start)/time.Microsecond))
+			}()
+		}
+		if err := unm.Unmarshal(buf, rsp); err != nil {
+			return maybeUnknownEnum(err)
+		}
+		return nil
+	}, c.logRetry(rpc), c.retryBudget, opts...)
+	if err == nil && isViewEnabled(SuccessfulAttemptView) {
+		recordStat(ctx, SuccessfulAttempt, rpc, int64(attempts))
+	}
+	if isViewEnabled(CallsTotalView) {
+		tags := append(c.locationTags(linkSegment(u.Path, "projects"), linkSegment(u.Path, "zones")), tag.Upsert(tagKeyError, strconv.FormatBool(err != nil)))
+		recordStat(ctx, CallsTotal, rpc, 1, tags...)
+	}
+	err = maybeAuthError(err)
+	if err != nil && (c.config.IncludeRequestInErrors || c.config.IncludeResponseInErrors) {
+		err = &RequestError{Method: method, URL: sanitizeURL(u), Status: rspStatus, Header: rspHeader, Err: err}
+	}
+	return err
+}
+
+// rebase replaces the "/compute/v1" prefix of the path of u with the
+// configured DisksClientConfig.BasePath, if any.
+func (c *disksRESTClient) rebase(u *url.URL) {
+	if c.apiPath == "" {
+		return
+	}
+	if u.Path == c.apiPath || strings.HasPrefix(u.Path, c.apiPath+"/") {
+		u.Path = c.rebasedPath + strings.TrimPrefix(u.Path, c.apiPath)
+	}
+}
+
+// checkDeadline logs and counts a call of the disks method rpc whose context
+// has no deadline.
+func (c *disksRESTClient) checkDeadline(ctx context.Context, rpc string) {
+	if _, ok := ctx.Deadline(); ok {
+		return
+	}
+	c.config.logf("compute: request method=%s has no context deadline", rpc)
+	if isViewEnabled(MissingDeadlineCountView) {
+		recordStat(ctx, MissingDeadlineCount, rpc, 1)
+	}
+}
+
+// getZoneOperation returns the zone operation with the given name, retrying
+// on transient errors like the idempotent disks methods.
+func (c *disksRESTClient) getZoneOperation(ctx context.Context, project, zone, operation string) (*computepb.Operation, error) {
+	baseUrl, _ := url.Parse(c.endpoint)
+	baseUrl.Path += fmt.Sprintf("/compute/v1/projects/%v/zones/%v/operations/%v", project, zone, operation)
+
+	rsp := &computepb.Operation{}
+	if err := c.do(ctx, zoneOperationsGet.rpc, "GET", baseUrl, nil, rsp, defaultDisksRetry()); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// logf writes a diagnostic message to the configured logger, if any.
+func (c *disksRESTClient) logf(format string, v ...interface{}) {
+	c.config.logf(format, v...)
+}
+
+// systemClock returns the clock the client pauses and times with.
+func (c *disksRESTClient) systemClock() clock {
+	return c.config.systemClock()
+}
+
+// logRetry returns a function that logs retries of the disks method rpc to
+// the configured logger, or nil if no logger is configured.
+func (c *disksRESTClient) logRetry(rpc string) func(int, error, time.Duration) {
+	if c.config.Logger == nil {
+		return nil
+	}
+	return func(attempt int, err error, pause time.Duration) {
+		c.config.Logger.Printf("compute: retrying request method=%s attempt=%d delay=%v error=%q", rpc, attempt, pause, err)
+	}
+}
+
+// versionREST returns the version of the google.golang.org/api module, which
+// provides the REST transport, as recorded in the build information of the
+// running binary. It returns "UNKNOWN" if the version cannot be determined.
+func versionREST() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "UNKNOWN"
+	}
+	for _, m := range info.Deps {
+		if m.Path != "google.golang.org/api" {
+			continue
+		}
+		if m.Replace != nil && m.Replace.Version != "" {
+			return strings.TrimPrefix(m.Replace.Version, "v")
+		}
+		if m.Version != "" {
+			return strings.TrimPrefix(m.Version, "v")
+		}
+	}
+	return "UNKNOWN"
+}
+
+// withDefaults returns req with its project and zone fields, if it has them,
+// set to DefaultProject and DefaultZone where the request leaves them empty.
+// req itself is not modified; a copy is returned if any field is set. It
+// returns an error if a field is empty in both the request and the client
+// configuration.
+func (c *disksRESTClient) withDefaults(req proto.Message) (proto.Message, error) {
+	m := req.ProtoReflect()
+	copied := false
+	for _, d := range []struct {
+		field  protoreflect.Name
+		option string
+		value  string
+	}{
+		{"project", "DefaultProject", c.config.DefaultProject},
+		{"zone", "DefaultZone", c.config.DefaultZone},
+	} {
+		fd := m.Descriptor().Fields().ByName(d.field)
+		if fd == nil || m.Get(fd).String() != "" {
+			continue
+		}
+		if d.value == "" {
+			return nil, fmt.Errorf("compute: %s is not set on the %s or in DisksClientConfig.%s", d.field, m.Descriptor().Name(), d.option)
+		}
+		if !copied {
+			if m.IsValid() {
+				req = proto.Clone(req)
+			} else {
+				req = m.Type().New().Interface()
+			}
+			m = req.ProtoReflect()
+			copied = true
+		}
+		m.Set(fd, protoreflect.ValueOfString(d.value))
+	}
+	return req, nil
+}
+
+const (
+	// minPageSize, initialPageSize and maxPageSize bound the page sizes
+	// chosen by a pageSizer. 500 is the largest maxResults Compute accepts.
+	minPageSize     = 10
+	initialPageSize = 100
+	maxPageSize     = 500
+
+	// fastPageLatency and slowPageLatency are the page latencies below which
+	// a pageSizer grows the page size, and above which it shrinks it.
+	fastPageLatency = time.Second
+	slowPageLatency = 4 * time.Second
+)
+
+// pageSizer chooses the page size of a listing from the latency and size of
+// its previous pages, for DisksClientConfig.AdaptivePageSize.
+type pageSizer struct {
+	size uint32
+}
+
+func newPageSizer() *pageSizer {
+	return &pageSizer{size: initialPageSize}
+}
+
+// observe adjusts the page size after a page of n items took d to fetch. The
+// size doubles after a full page that was fetched quickly, and halves after
+// a page that was slow to fetch, within [minPageSize, maxPageSize]. A page
+// with fewer items than requested is the last one, or was cut short by the
+// server, so it gives no reason to grow.
+func (s *pageSizer) observe(n int, d time.Duration) {
+	switch {
+	case d > slowPageLatency:
+		if s.size /= 2; s.size < minPageSize {
+			s.size = minPageSize
+		}
+	case d < fastPageLatency && n >= int(s.size):
+		if s.size *= 2; s.size > maxPageSize {
+			s.size = maxPageSize
+		}
+	}
+}
@@ -0,0 +1,453 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/xerrors"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// PollOptions configures how WaitForOperation and WaitForDiskReady poll for
+// the state they wait for. They are separate from the call options of the
+// individual requests, which still apply to each poll. A nil *PollOptions, or
+// a zero field, selects the default value.
+type PollOptions struct {
+	// InitialInterval is the pause before the second poll. Each following
+	// pause is twice as long as the previous one, up to MaxInterval.
+	//
+	// Defaults to 1s.
+	InitialInterval time.Duration
+
+	// MaxInterval is the longest pause between two polls.
+	//
+	// Defaults to 20s.
+	MaxInterval time.Duration
+
+	// Timeout is the longest time to wait overall. The wait also ends when
+	// the context passed to the helper is done, whichever comes first.
+	//
+	// Defaults to 10m.
+	Timeout time.Duration
+}
+
+const (
+	defaultPollInitialInterval = time.Second
+	defaultPollMaxInterval     = 20 * time.Second
+	defaultPollTimeout         = 10 * time.Minute
+)
+
+// withDefaults returns a copy of po with the zero fields set to their
+// default values.
+func (po *PollOptions) withDefaults() PollOptions {
+	var o PollOptions
+	if po != nil {
+		o = *po
+	}
+	if o.InitialInterval <= 0 {
+		o.InitialInterval = defaultPollInitialInterval
+	}
+	if o.MaxInterval <= 0 {
+		o.MaxInterval = defaultPollMaxInterval
+	}
+	if o.MaxInterval < o.InitialInterval {
+		o.MaxInterval = o.InitialInterval
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultPollTimeout
+	}
+	return o
+}
+
+// poll calls f until it reports done or fails, pausing between calls on clk
+// as configured by po.
+func (po *PollOptions) poll(ctx context.Context, clk clock, f func(context.Context) (done bool, err error)) error {
+	o := po.withDefaults()
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+	pause := o.InitialInterval
+	for {
+		done, err := f(ctx)
+		if err != nil || done {
+			return err
+		}
+		if err := sleep(ctx, clk, pause); err != nil {
+			return err
+		}
+		if pause *= 2; pause > o.MaxInterval {
+			pause = o.MaxInterval
+		}
+	}
+}
+
+// WaitForOperation polls the zonal operation op, such as the one returned by
+// Insert, until it is done, and returns its final state. If the operation
+// failed, the final state is returned together with an error describing the
+// failure.
+//
+// The time spent waiting and the number of polls are recorded in the
+// OperationWaitDuration and OperationPollCount metrics, if enabled.
+func (c *DisksClient) WaitForOperation(ctx context.Context, op *Operation, po *PollOptions) (*Operation, error) {
+	last, err := c.waitForOperation(ctx, op, po, func(op *computepb.Operation) (bool, error) {
+		return op.GetStatus() == computepb.Operation_DONE, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Operation{proto: last}, operationError(last)
+}
+
+// WaitForOperationFunc polls the zonal operation op until cond, which is
+// called with the state of the operation before each poll and after each
+// poll, returns true or an error, and returns the state cond accepted. It
+// lets a caller wait for a condition other than completion, such as the
+// progress of the operation reaching a threshold. The caller's cond controls
+// termination: polling stops as soon as it returns true or an error, and the
+// error it returns, if any, is returned as is.
+//
+// Polling also stops when the operation is done without cond returning true,
+// as its state can no longer change; the error then describes the failure of
+// the operation, if it failed. Like WaitForOperation, polling backs off
+// according to po and stops when ctx is done.
+func (c *DisksClient) WaitForOperationFunc(ctx context.Context, op *Operation, po *PollOptions, cond func(*computepb.Operation) (done bool, err error)) (*Operation, error) {
+	last, err := c.waitForOperation(ctx, op, po, cond)
+	if err != nil {
+		return nil, err
+	}
+	return &Operation{proto: last}, nil
+}
+
+// waitForOperation polls the zonal operation op until cond returns true or an
+// error, and returns the state cond accepted. If the operation is done before
+// cond returns true, an error is returned.
+func (c *DisksClient) waitForOperation(ctx context.Context, op *Operation, po *PollOptions, cond func(*computepb.Operation) (bool, error)) (*computepb.Operation, error) {
+	project := linkSegment(op.Proto().GetSelfLink(), "projects")
+	zone := linkSegment(op.Proto().GetSelfLink(), "zones")
+	if project == "" || zone == "" {
+		return nil, fmt.Errorf("compute: operation %q is not a zonal operation", op.Proto().GetSelfLink())
+	}
+	last := op.Proto()
+	clk := c.internalClient.systemClock()
+	start := clk.Now()
+	polls := 0
+	check := func() (bool, error) {
+		if done, err := cond(last); err != nil || done {
+			return true, err
+		}
+		if last.GetStatus() == computepb.Operation_DONE {
+			if err := operationError(last); err != nil {
+				return true, err
+			}
+			return true, fmt.Errorf("compute: operation %q is done and the condition was not met", last.GetName())
+		}
+		return false, nil
+	}
+	err := po.poll(ctx, clk, func(ctx context.Context) (bool, error) {
+		if polls == 0 {
+			if done, err := check(); err != nil || done {
+				return true, err
+			}
+		}
+		polls++
+		rsp, err := c.internalClient.getZoneOperation(ctx, project, zone, last.GetName())
+		if err != nil {
+			return false, err
+		}
+		last = rsp
+		return check()
+	})
+	recordOperationWait(ctx, last.GetOperationType(), clk.Now().Sub(start), polls, c.internalClient.locationTags(project, zone)...)
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// ResumeOperation polls the zonal operation opName in the given project and
+// zone until it is done, and returns its final state, like WaitForOperation.
+// It lets a process resume waiting for an operation it did not start, such
+// as one started by a previous run of a worker that recorded the name, zone
+// and project of the operation. If the operation failed, the final state is
+// returned together with an error describing the failure. Polling backs off
+// as with the default PollOptions, and stops when ctx is done.
+func (c *DisksClient) ResumeOperation(ctx context.Context, project, zone, opName string) (*computepb.Operation, error) {
+	if project == "" || zone == "" || opName == "" {
+		return nil, fmt.Errorf("compute: cannot resume operation %q in project %q and zone %q", opName, project, zone)
+	}
+	op := &Operation{proto: &computepb.Operation{
+		Name:     &opName,
+		SelfLink: proto.String(fmt.Sprintf("projects/%s/zones/%s/operations/%s", project, zone, opName)),
+	}}
+	done, err := c.WaitForOperation(ctx, op, nil)
+	if done == nil {
+		return nil, err
+	}
+	return done.Proto(), err
+}
+
+// defaultWaitConcurrency is the number of operations WaitForOperations polls
+// at once.
+const defaultWaitConcurrency = 10
+
+// OperationsError is returned by WaitForOperations when some of the
+// operations failed, or could not be waited for.
+type OperationsError struct {
+	// Errs holds the error of each operation, at the index of the operation
+	// in the slice passed to WaitForOperations. It is nil for the operations
+	// that succeeded.
+	Errs []error
+}
+
+func (e *OperationsError) Error() string {
+	var msgs []string
+	for _, err := range e.Errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return fmt.Sprintf("compute: %d of %d operations failed: %s", len(msgs), len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// WaitForOperations polls the zonal operations ops in the given project and
+// zone, such as those returned by several Inserts, until they are all done,
+// polling at most 10 operations at a time. It returns the final state of
+// each operation, at the index of the operation in ops.
+//
+// If some of the operations failed, or could not be polled, it also returns
+// an *OperationsError holding the error of each operation. The final state
+// of an operation that failed is returned with its error, but that of an
+// operation that could not be polled is nil. When ctx is done, no more
+// operations are polled, and the operations not done yet are reported with
+// the error of ctx.
+func (c *DisksClient) WaitForOperations(ctx context.Context, project, zone string, ops []*computepb.Operation) ([]*computepb.Operation, error) {
+	var (
+		wg   sync.WaitGroup
+		done = make([]*computepb.Operation, len(ops))
+		errs = make([]error, len(ops))
+		sem  = make(chan struct{}, defaultWaitConcurrency)
+	)
+	for i, op := range ops {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, op *computepb.Operation) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if op.GetStatus() == computepb.Operation_DONE {
+				done[i], errs[i] = op, operationError(op)
+			} else {
+				done[i], errs[i] = c.ResumeOperation(ctx, project, zone, op.GetName())
+			}
+		}(i, op)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return done, &OperationsError{Errs: errs}
+		}
+	}
+	return done, nil
+}
+
+// WaitForDiskReady polls the disk until its status is READY, and returns it.
+// It returns an error if the status of the disk becomes FAILED.
+func (c *DisksClient) WaitForDiskReady(ctx context.Context, project, zone, disk string, po *PollOptions) (*computepb.Disk, error) {
+	var d *computepb.Disk
+	err := po.poll(ctx, c.internalClient.systemClock(), func(ctx context.Context) (bool, error) {
+		var err error
+		d, err = c.Get(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: disk})
+		if err != nil {
+			return false, err
+		}
+		if IsDiskFailed(d) {
+			return false, fmt.Errorf("compute: creation of disk %q failed", disk)
+		}
+		return IsDiskReady(d), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetTargetDisk reads the disk that the operation op, such as the one
+// returned by Insert or Resize, applies to, as named by its TargetLink. It is
+// typically called once WaitForOperation returns, to obtain the updated
+// disk. It returns an error if op has no TargetLink, or if its target is not
+// a zonal disk.
+func (c *DisksClient) GetTargetDisk(ctx context.Context, op *Operation, opts ...gax.CallOption) (*computepb.Disk, error) {
+	link := op.Proto().GetTargetLink()
+	if link == "" {
+		return nil, fmt.Errorf("compute: operation %s has no target link", op.Proto().GetName())
+	}
+	project := linkSegment(link, "projects")
+	zone := linkSegment(link, "zones")
+	disk := linkSegment(link, "disks")
+	if project == "" || zone == "" || disk == "" {
+		return nil, fmt.Errorf("compute: target %q of operation %s is not a zonal disk", link, op.Proto().GetName())
+	}
+	return c.Get(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: disk}, opts...)
+}
+
+// DiskInUseError is returned for a disk that is still attached to one or more
+// instances, which prevents it from being deleted. The instances must be
+// detached from the disk, for example with InstancesClient.DetachDisk, before
+// it can be deleted.
+type DiskInUseError struct {
+	// Disk is the name of the disk.
+	Disk string
+
+	// Users are the URLs of the instances the disk is attached to.
+	Users []string
+
+	// Err is the error that ended the wait for the disk to be detached, such
+	// as context.DeadlineExceeded, or nil if there was no wait.
+	Err error
+}
+
+func (e *DiskInUseError) Error() string {
+	msg := fmt.Sprintf("compute: disk %q is attached to %s", e.Disk, strings.Join(e.Users, ", "))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DiskInUseError) Unwrap() error {
+	return e.Err
+}
+
+// DiskInUse returns a *DiskInUseError listing the instances d is attached
+// to, or nil if d is attached to none and can be deleted.
+func DiskInUse(d *computepb.Disk) error {
+	if len(d.GetUsers()) == 0 {
+		return nil
+	}
+	return &DiskInUseError{Disk: d.GetName(), Users: d.GetUsers()}
+}
+
+// WaitForDiskDetached polls the disk until it is no longer attached to any
+// instance, so that it can be deleted, and returns it. If the wait ends
+// before then, because of the timeout in po or because ctx is done, it
+// returns a *DiskInUseError listing the instances the disk is still attached
+// to.
+func (c *DisksClient) WaitForDiskDetached(ctx context.Context, project, zone, disk string, po *PollOptions) (*computepb.Disk, error) {
+	var d *computepb.Disk
+	err := po.poll(ctx, c.internalClient.systemClock(), func(ctx context.Context) (bool, error) {
+		rsp, err := c.Get(ctx, &computepb.GetDiskRequest{Project: project, Zone: zone, Disk: disk})
+		if err != nil {
+			return false, err
+		}
+		d = rsp
+		return len(d.GetUsers()) == 0, nil
+	})
+	if err != nil {
+		if len(d.GetUsers()) > 0 && (xerrors.Is(err, context.DeadlineExceeded) || xerrors.Is(err, context.Canceled)) {
+			return nil, &DiskInUseError{Disk: disk, Users: d.GetUsers(), Err: err}
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// OperationWarning is a warning attached to an operation by Compute, such as
+// a notice that the operation used a deprecated resource.
+type OperationWarning struct {
+	// Code is the warning code, such as "DEPRECATED_RESOURCE_USED".
+	Code string
+
+	// Message is a human-readable description of the warning.
+	Message string
+
+	// Data holds the metadata of the warning, such as the resource it is
+	// about, by key.
+	Data map[string]string
+}
+
+// OperationWarnings returns the warnings of op, in the order Compute returned
+// them, or nil if it has none.
+func OperationWarnings(op *computepb.Operation) []OperationWarning {
+	var warnings []OperationWarning
+	for _, w := range op.GetWarnings() {
+		ow := OperationWarning{Code: w.GetCode(), Message: w.GetMessage()}
+		if len(w.GetData()) > 0 {
+			ow.Data = make(map[string]string, len(w.GetData()))
+			for _, d := range w.GetData() {
+				ow.Data[d.GetKey()] = d.GetValue()
+			}
+		}
+		warnings = append(warnings, ow)
+	}
+	return warnings
+}
+
+// LogOperationWarnings writes each warning of op to the Logger of the client,
+// if it is set, and returns the warnings like OperationWarnings.
+func (c *DisksClient) LogOperationWarnings(op *computepb.Operation) []OperationWarning {
+	warnings := OperationWarnings(op)
+	for _, w := range warnings {
+		keys := make([]string, 0, len(w.Data))
+		for k := range w.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		data := make([]string, len(keys))
+		for i, k := range keys {
+			data[i] = k + "=" + w.Data[k]
+		}
+		c.internalClient.logf("compute: operation %s warning code=%s message=%q data=[%s]", op.GetName(), w.Code, w.Message, strings.Join(data, " "))
+	}
+	return warnings
+}
+
+// operationError returns an error describing the errors of the done
+// operation op, or nil if it succeeded.
+func operationError(op *computepb.Operation) error {
+	errs := op.GetError().GetErrors()
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = fmt.Sprintf("%s: %s", e.GetCode(), e.GetMessage())
+	}
+	return fmt.Errorf("compute: operation %s failed: %s", op.GetName(), strings.Join(msgs, "; "))
+}
+
+// linkSegment returns the path segment following the given collection, such
+// as "zones", in the resource URL link, or "" if there is none.
+func linkSegment(link, collection string) string {
+	parts := strings.Split(link, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == collection {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,467 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	"golang.org/x/xerrors"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// fastPoll polls without noticeable pauses.
+var fastPoll = &PollOptions{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Timeout: 5 * time.Second}
+
+func TestPollOptionsDefaults(t *testing.T) {
+	var po *PollOptions
+	got := po.withDefaults()
+	want := PollOptions{InitialInterval: time.Second, MaxInterval: 20 * time.Second, Timeout: 10 * time.Minute}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWaitForOperation(t *testing.T) {
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/compute/v1/projects/p/zones/z/operations/op"; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+		}
+		polls++
+		if polls < 3 {
+			w.Write([]byte(`{"name": "op", "status": "RUNNING"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "op", "status": "DONE", "error": {"errors": [{"code": "QUOTA_EXCEEDED", "message": "out of quota"}]}}`))
+	})
+	defer teardown()
+
+	op := &Operation{proto: &computepb.Operation{
+		Name:     proto.String("op"),
+		SelfLink: proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/operations/op"),
+		Status:   computepb.Operation_PENDING.Enum(),
+	}}
+	done, err := c.WaitForOperation(context.Background(), op, fastPoll)
+	if err == nil || !strings.Contains(err.Error(), "QUOTA_EXCEEDED: out of quota") {
+		t.Errorf("got error %v, want the error of the operation", err)
+	}
+	if done.Proto().GetStatus() != computepb.Operation_DONE {
+		t.Errorf("status = %v, want DONE", done.Proto().GetStatus())
+	}
+	if polls != 3 {
+		t.Errorf("got %d polls, want 3", polls)
+	}
+}
+
+func TestWaitForOperationFunc(t *testing.T) {
+	var progress []int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		p := 100
+		status := "DONE"
+		if len(progress) > 0 {
+			p, progress = progress[0], progress[1:]
+			status = "RUNNING"
+		}
+		fmt.Fprintf(w, `{"name": "op", "status": %q, "progress": %d}`, status, p)
+	})
+	defer teardown()
+	ctx := context.Background()
+	newOp := func() *Operation {
+		return &Operation{proto: &computepb.Operation{
+			Name:     proto.String("op"),
+			SelfLink: proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/operations/op"),
+			Status:   computepb.Operation_PENDING.Enum(),
+		}}
+	}
+	halfway := func(op *computepb.Operation) (bool, error) {
+		return op.GetProgress() > 50, nil
+	}
+
+	progress = []int{10, 40, 60, 80}
+	got, err := c.WaitForOperationFunc(ctx, newOp(), fastPoll, halfway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Proto().GetProgress() != 60 {
+		t.Errorf("progress = %d, want 60", got.Proto().GetProgress())
+	}
+
+	errStop := errors.New("stop")
+	progress = []int{10, 20}
+	calls := 0
+	_, err = c.WaitForOperationFunc(ctx, newOp(), fastPoll, func(op *computepb.Operation) (bool, error) {
+		if calls++; calls == 2 {
+			return false, errStop
+		}
+		return false, nil
+	})
+	if err != errStop {
+		t.Errorf("got error %v, want the error of the condition", err)
+	}
+
+	progress = []int{10}
+	_, err = c.WaitForOperationFunc(ctx, newOp(), fastPoll, func(op *computepb.Operation) (bool, error) {
+		return op.GetProgress() > 100, nil
+	})
+	if err == nil {
+		t.Error("got nil error for an operation done before the condition was met, want error")
+	}
+}
+
+func TestOperationWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{Logger: log.New(&buf, "", 0)}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	op := &computepb.Operation{
+		Name: proto.String("op"),
+		Warnings: []*computepb.Warnings{
+			{
+				Code:    proto.String("DEPRECATED_RESOURCE_USED"),
+				Message: proto.String("image is deprecated"),
+				Data: []*computepb.Data{
+					{Key: proto.String("resource"), Value: proto.String("images/old")},
+					{Key: proto.String("replacement"), Value: proto.String("images/new")},
+				},
+			},
+			{Code: proto.String("LARGE_DEPLOYMENT_WARNING"), Message: proto.String("large")},
+		},
+	}
+	want := []OperationWarning{
+		{
+			Code:    "DEPRECATED_RESOURCE_USED",
+			Message: "image is deprecated",
+			Data:    map[string]string{"resource": "images/old", "replacement": "images/new"},
+		},
+		{Code: "LARGE_DEPLOYMENT_WARNING", Message: "large"},
+	}
+	if diff := cmp.Diff(want, OperationWarnings(op)); diff != "" {
+		t.Errorf("warnings mismatch (-want +got):\n%s", diff)
+	}
+	if got := OperationWarnings(&computepb.Operation{}); got != nil {
+		t.Errorf("got warnings %v for an operation without warnings, want nil", got)
+	}
+
+	if diff := cmp.Diff(want, c.LogOperationWarnings(op)); diff != "" {
+		t.Errorf("logged warnings mismatch (-want +got):\n%s", diff)
+	}
+	wantLog := `compute: operation op warning code=DEPRECATED_RESOURCE_USED message="image is deprecated" data=[replacement=images/new resource=images/old]
+compute: operation op warning code=LARGE_DEPLOYMENT_WARNING message="large" data=[]
+`
+	if got := buf.String(); got != wantLog {
+		t.Errorf("got log %q, want %q", got, wantLog)
+	}
+}
+
+func TestWaitForOperationTimeout(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "op", "status": "RUNNING"}`))
+	})
+	defer teardown()
+
+	op := &Operation{proto: &computepb.Operation{
+		Name:     proto.String("op"),
+		SelfLink: proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/operations/op"),
+	}}
+	po := &PollOptions{InitialInterval: time.Millisecond, Timeout: 50 * time.Millisecond}
+	if _, err := c.WaitForOperation(context.Background(), op, po); !xerrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestResumeOperation(t *testing.T) {
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{clock: newFakeClock()}, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/compute/v1/projects/p/zones/z/operations/op"; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+		}
+		polls++
+		if polls < 2 {
+			w.Write([]byte(`{"name": "op", "status": "RUNNING"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "op", "status": "DONE", "targetLink": "projects/p/zones/z/disks/d"}`))
+	})
+	defer teardown()
+
+	op, err := c.ResumeOperation(context.Background(), "p", "z", "op")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.GetStatus() != computepb.Operation_DONE || op.GetTargetLink() != "projects/p/zones/z/disks/d" {
+		t.Errorf("got %v, want the final state of the operation", op)
+	}
+	if polls != 2 {
+		t.Errorf("got %d polls, want 2", polls)
+	}
+
+	if _, err := c.ResumeOperation(context.Background(), "p", "", "op"); err == nil {
+		t.Error("got no error for an operation without a zone")
+	}
+}
+
+func TestWaitForOperations(t *testing.T) {
+	var (
+		mu                sync.Mutex
+		polls             = make(map[string]int)
+		inFlight, maxSeen int
+	)
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{clock: newFakeClock()}, func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		mu.Lock()
+		polls[name]++
+		n := polls[name]
+		if inFlight++; inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		switch {
+		case n < 2:
+			fmt.Fprintf(w, `{"name": %q, "status": "RUNNING"}`, name)
+		case name == "op-failed":
+			fmt.Fprintf(w, `{"name": %q, "status": "DONE", "error": {"errors": [{"code": "QUOTA_EXCEEDED", "message": "no quota"}]}}`, name)
+		default:
+			fmt.Fprintf(w, `{"name": %q, "status": "DONE"}`, name)
+		}
+	})
+	defer teardown()
+
+	var ops []*computepb.Operation
+	for i := 0; i < 20; i++ {
+		ops = append(ops, &computepb.Operation{Name: proto.String(fmt.Sprintf("op-%d", i))})
+	}
+	ops = append(ops,
+		&computepb.Operation{Name: proto.String("op-done"), Status: computepb.Operation_DONE.Enum()},
+		&computepb.Operation{Name: proto.String("op-failed")})
+	done, err := c.WaitForOperations(context.Background(), "p", "z", ops)
+	var opsErr *OperationsError
+	if !xerrors.As(err, &opsErr) {
+		t.Fatalf("got %v, want an *OperationsError", err)
+	}
+	for i, op := range done {
+		if op.GetName() != ops[i].GetName() || op.GetStatus() != computepb.Operation_DONE {
+			t.Errorf("%d: got %v, want the final state of %s", i, op, ops[i].GetName())
+		}
+		if wantErr := ops[i].GetName() == "op-failed"; (opsErr.Errs[i] != nil) != wantErr {
+			t.Errorf("%s: got error %v, want an error: %t", ops[i].GetName(), opsErr.Errs[i], wantErr)
+		}
+	}
+	if polls["op-done"] != 0 {
+		t.Errorf("got %d polls of a done operation, want none", polls["op-done"])
+	}
+	if maxSeen > defaultWaitConcurrency {
+		t.Errorf("got %d operations polled at once, want at most %d", maxSeen, defaultWaitConcurrency)
+	}
+
+	if _, err := c.WaitForOperations(context.Background(), "p", "z", ops[:20]); err != nil {
+		t.Errorf("got %v, want no error when all operations succeed", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.WaitForOperations(ctx, "p", "z", ops[:2]); !xerrors.As(err, &opsErr) || !xerrors.Is(opsErr.Errs[0], context.Canceled) {
+		t.Errorf("got %v, want the error of the context", err)
+	}
+}
+
+func TestWaitForDiskReady(t *testing.T) {
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			w.Write([]byte(`{"name": "d", "status": "CREATING"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "d", "status": "READY"}`))
+	})
+	defer teardown()
+
+	d, err := c.WaitForDiskReady(context.Background(), "p", "z", "d", fastPoll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.GetStatus() != "READY" || polls != 2 {
+		t.Errorf("got status %q after %d polls, want READY after 2", d.GetStatus(), polls)
+	}
+}
+
+func TestWaitForDiskDetached(t *testing.T) {
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			w.Write([]byte(`{"name": "d", "users": ["projects/p/zones/z/instances/i"]}`))
+			return
+		}
+		w.Write([]byte(`{"name": "d"}`))
+	})
+	defer teardown()
+
+	d, err := c.WaitForDiskDetached(context.Background(), "p", "z", "d", fastPoll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DiskInUse(d); err != nil {
+		t.Errorf("DiskInUse = %v, want nil", err)
+	}
+	if polls != 3 {
+		t.Errorf("got %d polls, want 3", polls)
+	}
+}
+
+func TestWaitForDiskDetachedTimeout(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "d", "users": ["projects/p/zones/z/instances/a", "projects/p/zones/z/instances/b"]}`))
+	})
+	defer teardown()
+
+	po := &PollOptions{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, Timeout: 20 * time.Millisecond}
+	_, err := c.WaitForDiskDetached(context.Background(), "p", "z", "d", po)
+	var inUse *DiskInUseError
+	if !xerrors.As(err, &inUse) {
+		t.Fatalf("got %v, want a *DiskInUseError", err)
+	}
+	want := []string{"projects/p/zones/z/instances/a", "projects/p/zones/z/instances/b"}
+	if diff := cmp.Diff(want, inUse.Users); diff != "" {
+		t.Errorf("users mismatch (-want +got):\n%s", diff)
+	}
+	if !xerrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want an error matching context.DeadlineExceeded", err)
+	}
+}
+
+func TestGetTargetDisk(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.URL.Path, "/compute/v1/projects/p/zones/z/disks/d"; got != want {
+			t.Errorf("path = %q, want %q", got, want)
+		}
+		w.Write([]byte(`{"name": "d", "sizeGb": "20"}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	op := &Operation{proto: &computepb.Operation{
+		Name:       proto.String("op"),
+		TargetLink: proto.String("https://www.googleapis.com/compute/v1/projects/p/zones/z/disks/d"),
+	}}
+	d, err := c.GetTargetDisk(ctx, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.GetSizeGb() != 20 {
+		t.Errorf("SizeGb = %d, want 20", d.GetSizeGb())
+	}
+
+	for _, link := range []string{"", "https://www.googleapis.com/compute/v1/projects/p/global/images/i"} {
+		op := &Operation{proto: &computepb.Operation{Name: proto.String("op"), TargetLink: proto.String(link)}}
+		if _, err := c.GetTargetDisk(ctx, op); err == nil {
+			t.Errorf("target link %q: got nil error, want error", link)
+		}
+	}
+}
+
+func TestWaitForOperationStats(t *testing.T) {
+	if err := EnableOperationWaitViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableOperationWaitViews()
+
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 2 {
+			w.Write([]byte(`{"name": "op", "operationType": "insert", "status": "RUNNING"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "op", "operationType": "insert", "status": "DONE"}`))
+	})
+	defer teardown()
+
+	op := &Operation{proto: &computepb.Operation{
+		Name:          proto.String("op"),
+		OperationType: proto.String("insert"),
+		SelfLink:      proto.String("https://compute.googleapis.com/compute/v1/projects/p/zones/z/operations/op"),
+		Status:        computepb.Operation_PENDING.Enum(),
+	}}
+	if _, err := c.WaitForOperation(context.Background(), op, fastPoll); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(OperationPollCountView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if got, want := rows[0].Tags, []tag.Tag{{Key: tagKeyOperationType, Value: "insert"}}; !cmp.Equal(got, want, cmp.Comparer(func(a, b tag.Key) bool { return a.Name() == b.Name() })) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+	if got := rows[0].Data.(*view.DistributionData).Mean; got != 2 {
+		t.Errorf("poll count = %v, want 2", got)
+	}
+	rows, err = view.RetrieveData(OperationWaitDurationView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Data.(*view.DistributionData).Count != 1 {
+		t.Errorf("got rows %v, want a single recorded wait", rows)
+	}
+}
+
+func TestWaitForOperationWithFakeClock(t *testing.T) {
+	clk := newFakeClock()
+	var polls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{clock: clk}, func(w http.ResponseWriter, r *http.Request) {
+		if polls++; polls < 5 {
+			w.Write([]byte(`{"name": "op", "status": "RUNNING"}`))
+			return
+		}
+		w.Write([]byte(`{"name": "op", "status": "DONE"}`))
+	})
+	defer teardown()
+
+	op := &Operation{proto: &computepb.Operation{
+		Name:     proto.String("op"),
+		SelfLink: proto.String("projects/p/zones/z/operations/op"),
+	}}
+	po := &PollOptions{InitialInterval: time.Minute, MaxInterval: 5 * time.Minute}
+	if _, err := c.WaitForOperation(context.Background(), op, po); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	if diff := cmp.Diff(want, clk.waits); diff != "" {
+		t.Errorf("pauses mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrAuth is matched, using errors.Is, by the errors of requests that failed
+// because the credentials of the client were rejected or could not be
+// refreshed, as opposed to errors reported by the Compute API itself. The
+// underlying error, such as a *googleapi.Error with status 401 or an
+// *oauth2.RetrieveError, remains available through errors.As.
+var ErrAuth = errors.New("compute: authentication failed")
+
+// authError wraps an error caused by the credentials of the client.
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return ErrAuth.Error() + ": " + e.err.Error()
+}
+
+func (e *authError) Is(target error) bool {
+	return target == ErrAuth
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// ErrRetryBudgetExhausted is matched, using errors.Is, by the errors of
+// requests that failed with a retryable error but were not retried because
+// the client's DisksClientConfig.RetryBudget had no retries left. The error of
+// the last attempt remains available through errors.As.
+var ErrRetryBudgetExhausted = errors.New("compute: retry budget exhausted")
+
+// retryBudgetError wraps the error of a request that was not retried because
+// the retry budget was exhausted.
+type retryBudgetError struct {
+	err error
+}
+
+func (e *retryBudgetError) Error() string {
+	return ErrRetryBudgetExhausted.Error() + ": " + e.err.Error()
+}
+
+func (e *retryBudgetError) Is(target error) bool {
+	return target == ErrRetryBudgetExhausted
+}
+
+func (e *retryBudgetError) Unwrap() error {
+	return e.err
+}
+
+// ErrCircuitOpen is returned for requests that were not sent because the
+// circuit breaker configured with DisksClientConfig.CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("compute: circuit breaker is open")
+
+// ErrTransportUnavailable is matched, using errors.Is, by the error of
+// NewDisksClientWithConfig when the transport selected with
+// DisksClientConfig.Transport is not available.
+var ErrTransportUnavailable = errors.New("compute: transport unavailable")
+
+// ErrScanLimitReached is returned, together with the results found so far,
+// by listing helpers that stopped scanning because they reached the number of
+// resources they were allowed to scan, such as
+// InstanceDisksOptions.MaxScanned.
+var ErrScanLimitReached = errors.New("compute: scan limit reached")
+
+// maybeAuthError wraps err so that it matches ErrAuth if it is the result of
+// the server rejecting the credentials of the client, or of a failure to
+// refresh its OAuth2 token.
+func maybeAuthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var gerr *googleapi.Error
+	if xerrors.As(err, &gerr) && gerr.Code == http.StatusUnauthorized {
+		return &authError{err: err}
+	}
+	var rerr *oauth2.RetrieveError
+	if xerrors.As(err, &rerr) {
+		return &authError{err: err}
+	}
+	return err
+}
+
+// RequestError is the error returned by DisksClient methods when
+// DisksClientConfig.IncludeRequestInErrors or IncludeResponseInErrors is
+// set. It records the request
+// that failed alongside the underlying error, which remains available
+// through errors.Is and errors.As.
+type RequestError struct {
+	// Method is the HTTP method of the request, such as "GET".
+	Method string
+
+	// URL is the URL of the request, without user information and with the
+	// values of the access_token, key, oauth_token and token query
+	// parameters redacted.
+	URL string
+
+	// Status is the status line of the response to the last attempt of the
+	// request, such as "403 Forbidden", if IncludeResponseInErrors is set
+	// and a response was received.
+	Status string
+
+	// Header holds the headers of the response to the last attempt of the
+	// request, such as quota and debugging headers, if
+	// IncludeResponseInErrors is set and a response was received. The
+	// response body is not retained.
+	Header http.Header
+
+	// Err is the error of the request.
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// sensitiveQueryParams are the query parameters that can carry credentials,
+// and whose values are redacted from the URL of a RequestError.
+var sensitiveQueryParams = []string{"access_token", "key", "oauth_token", "token"}
+
+// sanitizeURL returns u as a string with any user information removed and
+// the values of the query parameters in sensitiveQueryParams replaced with
+// "REDACTED". Other query parameters, such as requestId, are kept.
+func sanitizeURL(u *url.URL) string {
+	s := *u
+	s.User = nil
+	q := s.Query()
+	for _, p := range sensitiveQueryParams {
+		if _, ok := q[p]; ok {
+			q.Set(p, "REDACTED")
+		}
+	}
+	s.RawQuery = q.Encode()
+	return s.String()
+}
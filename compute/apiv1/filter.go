@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// filterFieldRE matches the field names of filter expressions, such as
+// "status", "scheduling.automaticRestart" or "labels.my-label".
+var filterFieldRE = regexp.MustCompile(`^[a-zA-Z_][-a-zA-Z0-9_]*(\.[a-zA-Z_][-a-zA-Z0-9_]*)*$`)
+
+// filterValueEscaper escapes string values for the double quotes they are
+// enclosed in.
+var filterValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// Filter is an expression of the filter language of the Compute list
+// methods, for the Filter field of requests such as ListDisksRequest. It is
+// built from comparisons, such as Eq, combined with And and Or, which
+// enclose each of their operands in parentheses so that nested expressions
+// keep their grouping. For example:
+//
+//	f := compute.And(
+//		compute.Or(compute.Eq("status", "READY"), compute.Eq("status", "CREATING")),
+//		compute.Eq("labels.env", "prod"),
+//	)
+//	expr, err := f.Expr()
+//	// ((status = "READY") OR (status = "CREATING")) AND (labels.env = "prod")
+//
+// Any error in building the filter is reported by Expr.
+type Filter struct {
+	expr string
+	err  error
+}
+
+// Eq returns a filter that selects the resources whose field equals value.
+func Eq(field string, value interface{}) Filter {
+	return compare(field, "=", value)
+}
+
+// Ne returns a filter that selects the resources whose field does not equal
+// value.
+func Ne(field string, value interface{}) Filter {
+	return compare(field, "!=", value)
+}
+
+// Gt returns a filter that selects the resources whose field is greater
+// than value.
+func Gt(field string, value interface{}) Filter {
+	return compare(field, ">", value)
+}
+
+// Lt returns a filter that selects the resources whose field is less than
+// value.
+func Lt(field string, value interface{}) Filter {
+	return compare(field, "<", value)
+}
+
+// And returns a filter that selects the resources selected by all of the
+// given filters.
+func And(filters ...Filter) Filter {
+	return combine("AND", filters)
+}
+
+// Or returns a filter that selects the resources selected by any of the
+// given filters.
+func Or(filters ...Filter) Filter {
+	return combine("OR", filters)
+}
+
+// Expr returns the filter expression, or an error if a field name or value
+// of the filter is invalid.
+func (f Filter) Expr() (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.expr == "" {
+		return "", fmt.Errorf("compute: empty filter")
+	}
+	return f.expr, nil
+}
+
+// compare returns a filter that compares field to value with op. String
+// values are quoted and escaped; booleans and numbers are not.
+func compare(field, op string, value interface{}) Filter {
+	if !filterFieldRE.MatchString(field) {
+		return Filter{err: fmt.Errorf("compute: invalid filter field %q", field)}
+	}
+	var v string
+	switch value := value.(type) {
+	case string:
+		v = `"` + filterValueEscaper.Replace(value) + `"`
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		v = fmt.Sprint(value)
+	default:
+		return Filter{err: fmt.Errorf("compute: unsupported value %v of type %T for filter field %q", value, value, field)}
+	}
+	return Filter{expr: fmt.Sprintf("%s %s %s", field, op, v)}
+}
+
+// combine returns a filter that joins filters with the boolean operator op,
+// each enclosed in parentheses. A single filter is returned as it is.
+func combine(op string, filters []Filter) Filter {
+	if len(filters) == 0 {
+		return Filter{err: fmt.Errorf("compute: %s of no filters", op)}
+	}
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	exprs := make([]string, len(filters))
+	for i, f := range filters {
+		expr, err := f.Expr()
+		if err != nil {
+			return Filter{err: err}
+		}
+		exprs[i] = "(" + expr + ")"
+	}
+	return Filter{expr: strings.Join(exprs, " "+op+" ")}
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		filter Filter
+		want   string
+	}{
+		{"comparison", Eq("status", "READY"), `status = "READY"`},
+		{"not equal", Ne("labels.env", "prod"), `labels.env != "prod"`},
+		{"number", Gt("sizeGb", 100), `sizeGb > 100`},
+		{"bool", Eq("scheduling.automaticRestart", true), `scheduling.automaticRestart = true`},
+		{"escaped value", Eq("description", `say "hi" \o/`), `description = "say \"hi\" \\o/"`},
+		{"single operand", Or(Eq("status", "READY")), `status = "READY"`},
+		{
+			"or",
+			Or(Eq("status", "READY"), Eq("status", "CREATING")),
+			`(status = "READY") OR (status = "CREATING")`,
+		},
+		{
+			"or within and",
+			And(Or(Eq("status", "READY"), Eq("status", "CREATING")), Eq("labels.env", "prod")),
+			`((status = "READY") OR (status = "CREATING")) AND (labels.env = "prod")`,
+		},
+		{
+			"and within or",
+			Or(And(Eq("a", 1), Eq("b", 2)), Lt("c", 3)),
+			`((a = 1) AND (b = 2)) OR (c < 3)`,
+		},
+		{
+			"nested three levels",
+			And(Eq("a", 1), Or(Eq("b", 2), And(Eq("c", 3), Eq("d", 4)))),
+			`(a = 1) AND ((b = 2) OR ((c = 3) AND (d = 4)))`,
+		},
+	} {
+		got, err := test.filter.Expr()
+		if err != nil {
+			t.Errorf("%s: %v", test.desc, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		filter Filter
+		want   string
+	}{
+		{"empty", Filter{}, "empty filter"},
+		{"invalid field", Eq("status = READY OR name", "x"), "invalid filter field"},
+		{"unsupported value", Eq("status", []string{"READY"}), "unsupported value"},
+		{"no operands", And(), "AND of no filters"},
+		{"nested error", Or(Eq("a", 1), And(Eq("b", 2), Eq("", 3))), "invalid filter field"},
+	} {
+		_, err := test.filter.Expr()
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got %v, want an error containing %q", test.desc, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+)
+
+// invoke calls the given function, retrying it according to the Retryer
+// resolved from opts. It behaves like gax.Invoke, except that the pause
+// between attempts never extends past the deadline of ctx.
+//
+// If onRetry is non-nil, it is called before each pause with the number of
+// the attempt that failed, starting at 1, its error and the length of the
+// pause.
+//
+// If budget is non-nil, each retry takes a token from it, and the error of
+// the last attempt is returned wrapped in a *retryBudgetError, without
+// retrying, once budget is exhausted.
+func invoke(ctx context.Context, clk clock, call func(context.Context) error, onRetry func(attempt int, err error, pause time.Duration), budget *retryBudget, opts ...gax.CallOption) error {
+	var settings gax.CallSettings
+	for _, opt := range opts {
+		opt.Resolve(&settings)
+	}
+	var retryer gax.Retryer
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil {
+			return nil
+		}
+		if settings.Retry == nil {
+			return err
+		}
+		if retryer == nil {
+			if retryer = settings.Retry(); retryer == nil {
+				return err
+			}
+		}
+		pause, ok := retryer.Retry(err)
+		if !ok {
+			return err
+		}
+		if !budget.take() {
+			return &retryBudgetError{err: err}
+		}
+		if onRetry != nil {
+			onRetry(attempt, err, pause)
+		}
+		if sleepErr := sleep(ctx, clk, pause); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+// retryBudget is a token bucket that limits the rate of retries shared by
+// all calls of a client. It is safe for concurrent use.
+type retryBudget struct {
+	rate  float64 // tokens added per second
+	burst float64 // maximum number of tokens
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newRetryBudget returns a full retryBudget with the given settings that
+// refills on clk, or nil if b is nil.
+func newRetryBudget(b *RetryBudget, clk clock) *retryBudget {
+	if b == nil {
+		return nil
+	}
+	return &retryBudget{
+		rate:   b.Rate,
+		burst:  float64(b.Burst),
+		tokens: float64(b.Burst),
+		now:    clk.Now,
+	}
+}
+
+// take reports whether a retry is allowed, and if so takes a token for it. A
+// nil retryBudget allows every retry.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// clock tells the time and measures pauses for the retry loop and the wait
+// helpers, so that tests can control the passage of time. Context deadlines,
+// including the Timeout of PollOptions, are always in real time.
+type clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock of the system.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// sleep pauses for d on clk, and returns ctx.Err() if ctx is done before d elapses.
+// If d would end past the deadline of ctx, it returns
+// context.DeadlineExceeded at once without sleeping, as the next attempt
+// could not start before the deadline.
+func sleep(ctx context.Context, clk clock, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if time.Until(deadline) < d {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return context.DeadlineExceeded
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clk.After(d):
+		return nil
+	}
+}
+
+// onHTTPCodes returns a Retryer that retries if and only if the previous
+// attempt returns a googleapi.Error whose status code is one of cc. Pause
+// times between retries are specified by bo.
+func onHTTPCodes(bo gax.Backoff, cc ...int) gax.Retryer {
+	codes := append([]int(nil), cc...)
+	return gax.OnErrorFunc(bo, func(err error) bool {
+		var gerr *googleapi.Error
+		if !xerrors.As(err, &gerr) {
+			return false
+		}
+		for _, c := range codes {
+			if gerr.Code == c {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,257 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// fixedRetryer retries every error after a fixed pause.
+type fixedRetryer time.Duration
+
+func (r fixedRetryer) Retry(err error) (time.Duration, bool) {
+	return time.Duration(r), true
+}
+
+func TestRetryDoesNotSleepPastDeadline(t *testing.T) {
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer teardown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"},
+		gax.WithRetry(func() gax.Retryer { return fixedRetryer(time.Minute) }))
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Get returned after %v, want it to return at once rather than wait for the deadline", elapsed)
+	}
+}
+
+// fakeClock is a clock whose time only advances when it is waited on: After
+// advances it by the given duration and fires at once.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// newFakeClock returns a fakeClock set to the current time, to be passed to a
+// client in DisksClientConfig.clock.
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func TestRetryWithFakeClock(t *testing.T) {
+	clk := newFakeClock()
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{clock: clk}, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls <= 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	start := time.Now()
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"},
+		gax.WithRetry(func() gax.Retryer { return fixedRetryer(time.Hour) })); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Get returned after %v, want the pauses to be taken on the fake clock", elapsed)
+	}
+	if diff := cmp.Diff([]time.Duration{time.Hour, time.Hour, time.Hour}, clk.waits); diff != "" {
+		t.Errorf("pauses mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSleepWithoutBudget(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	start := time.Now()
+	if err := sleep(ctx, realClock{}, time.Minute); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep returned after %v, want it to return immediately", elapsed)
+	}
+}
+
+func TestRetryMutatingMethods(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		config    DisksClientConfig
+		wantCalls int
+	}{
+		{"default", DisksClientConfig{}, 1},
+		{"enabled", DisksClientConfig{RetryMutatingMethods: []string{"Insert"}}, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var calls int
+			var requestIDs []string
+			c, teardown := newFakeDisksClient(t, test.config, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				requestIDs = append(requestIDs, r.URL.Query().Get("requestId"))
+				if calls == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte(`{}`))
+			})
+			defer teardown()
+
+			c.Insert(context.Background(), &computepb.InsertDiskRequest{Project: "p", Zone: "z"},
+				gax.WithRetry(func() gax.Retryer { return fixedRetryer(0) }))
+			if calls != test.wantCalls {
+				t.Fatalf("server received %d calls, want %d", calls, test.wantCalls)
+			}
+			if test.wantCalls > 1 {
+				if requestIDs[0] == "" || requestIDs[0] != requestIDs[1] {
+					t.Errorf("got request IDs %q, want the same generated ID on each attempt", requestIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryLogging(t *testing.T) {
+	var calls int
+	var buf bytes.Buffer
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{Logger: log.New(&buf, "", 0)}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"},
+		gax.WithRetry(func() gax.Retryer { return fixedRetryer(time.Millisecond) })); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"method=compute.disks.get", "attempt=1", "delay=1ms", "503"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log output %q does not contain %q", got, want)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{RetryBudget: &RetryBudget{Rate: 0.001, Burst: 1}}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, `{"error": {"code": 503, "message": "unavailable"}}`, http.StatusServiceUnavailable)
+	})
+	defer teardown()
+
+	retry := gax.WithRetry(func() gax.Retryer { return fixedRetryer(0) })
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+	for i, wantCalls := range []int{2, 3} {
+		_, err := c.Get(context.Background(), req, retry)
+		if !xerrors.Is(err, ErrRetryBudgetExhausted) {
+			t.Fatalf("call %d: got %v, want an error matching ErrRetryBudgetExhausted", i, err)
+		}
+		var gerr *googleapi.Error
+		if !xerrors.As(err, &gerr) || gerr.Code != http.StatusServiceUnavailable {
+			t.Errorf("call %d: got %v, want a wrapped *googleapi.Error with code 503", i, err)
+		}
+		if calls != wantCalls {
+			t.Errorf("call %d: server received %d calls in total, want %d", i, calls, wantCalls)
+		}
+	}
+}
+
+func TestRetryBudgetRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newRetryBudget(&RetryBudget{Rate: 2, Burst: 2}, realClock{})
+	b.now = func() time.Time { return now }
+	for i, want := range []bool{true, true, false} {
+		if got := b.take(); got != want {
+			t.Errorf("take %d = %v, want %v", i, got, want)
+		}
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !b.take() {
+		t.Error("take after refill = false, want true")
+	}
+	if b.take() {
+		t.Error("second take after refill = true, want false")
+	}
+	now = now.Add(time.Hour)
+	for i, want := range []bool{true, true, false} {
+		if got := b.take(); got != want {
+			t.Errorf("take %d after full refill = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestReplaceCallOptions(t *testing.T) {
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer teardown()
+
+	opts := *c.CallOptions
+	opts.Get = []gax.CallOption{}
+	c.CallOptions = &opts
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err == nil {
+		t.Fatal("got nil error, want the error of the request")
+	}
+	if calls != 1 {
+		t.Errorf("server received %d calls, want the replaced CallOptions not to retry", calls)
+	}
+}
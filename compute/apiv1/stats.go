@@ -0,0 +1,474 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+const statsPrefix = "cloud.google.com/go/compute/"
+
+var (
+	tagKeyError         = tag.MustNewKey("error")
+	tagKeyMethod        = tag.MustNewKey("method")
+	tagKeyOperationType = tag.MustNewKey("operation_type")
+	tagKeyProject       = tag.MustNewKey("project")
+	tagKeyZone          = tag.MustNewKey("zone")
+
+	// enabledViews tracks the views registered through this package, so that
+	// measures nobody is viewing are not recorded at all.
+	enabledViews = map[*view.View]bool{}
+	// mutex to avoid data race in reading/writing the above map
+	statsMu = sync.RWMutex{}
+)
+
+var (
+	// InFlightRequests is a measure of the change in the number of disks
+	// REST requests currently in flight. It is recorded as 1 when a request
+	// is sent and -1 when its response is received.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	InFlightRequests = stats.Int64(
+		statsPrefix+"in_flight_requests",
+		"Number of REST requests currently in flight",
+		stats.UnitDimensionless,
+	)
+
+	// InFlightRequestsView is a view of the number of requests currently in
+	// flight, by method, and by project and zone if
+	// DisksClientConfig.LocationMetricTags is set.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	InFlightRequestsView = &view.View{
+		Measure:     InFlightRequests,
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{tagKeyMethod, tagKeyProject, tagKeyZone},
+	}
+)
+
+var (
+	// OperationWaitDuration is a measure of how long WaitForOperation waited
+	// for an operation to be done, in milliseconds.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OperationWaitDuration = stats.Int64(
+		statsPrefix+"operation_wait_duration",
+		"Time spent waiting for an operation to be done",
+		stats.UnitMilliseconds,
+	)
+
+	// OperationPollCount is a measure of the number of times WaitForOperation
+	// polled an operation before it was done.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OperationPollCount = stats.Int64(
+		statsPrefix+"operation_poll_count",
+		"Number of polls made while waiting for an operation to be done",
+		stats.UnitDimensionless,
+	)
+
+	// OperationWaitDurationView is a view of the distribution of
+	// OperationWaitDuration values, by operation type, such as "insert", and
+	// by project and zone if DisksClientConfig.LocationMetricTags is set.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OperationWaitDurationView = &view.View{
+		Measure:     OperationWaitDuration,
+		Aggregation: view.Distribution(0.0, 1000.0, 2000.0, 5000.0, 10000.0, 20000.0, 30000.0, 60000.0, 120000.0, 300000.0, 600000.0),
+		TagKeys:     []tag.Key{tagKeyOperationType, tagKeyProject, tagKeyZone},
+	}
+
+	// OperationPollCountView is a view of the distribution of
+	// OperationPollCount values, by operation type, such as "insert", and by
+	// project and zone if DisksClientConfig.LocationMetricTags is set.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OperationPollCountView = &view.View{
+		Measure:     OperationPollCount,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 3.0, 5.0, 10.0, 20.0, 50.0, 100.0),
+		TagKeys:     []tag.Key{tagKeyOperationType, tagKeyProject, tagKeyZone},
+	}
+)
+
+var (
+	// MissingDeadlineCount is a measure of the number of calls made with a
+	// context that has no deadline, recorded when
+	// DisksClientConfig.CheckDeadlines is set.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	MissingDeadlineCount = stats.Int64(
+		statsPrefix+"missing_deadline_count",
+		"Number of calls made with a context without a deadline",
+		stats.UnitDimensionless,
+	)
+
+	// MissingDeadlineCountView is a view of the count of calls made with a
+	// context without a deadline, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	MissingDeadlineCountView = &view.View{
+		Measure:     MissingDeadlineCount,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{tagKeyMethod},
+	}
+)
+
+var (
+	// CircuitBreakerState is a measure of the state of the circuit breaker
+	// configured with DisksClientConfig.CircuitBreaker, recorded whenever it
+	// changes: 0 when closed, 1 when half-open and 2 when open.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CircuitBreakerState = stats.Int64(
+		statsPrefix+"circuit_breaker_state",
+		"State of the circuit breaker: 0 closed, 1 half-open, 2 open",
+		stats.UnitDimensionless,
+	)
+
+	// CircuitBreakerStateView is a view of the last recorded state of the
+	// circuit breaker.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CircuitBreakerStateView = &view.View{
+		Measure:     CircuitBreakerState,
+		Aggregation: view.LastValue(),
+	}
+)
+
+var (
+	// CallsByProject is a measure of the calls made by disks clients,
+	// recorded once per call, however many times it is retried.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CallsByProject = stats.Int64(
+		statsPrefix+"calls_by_project",
+		"Number of calls made, by project",
+		stats.UnitDimensionless,
+	)
+
+	// CallsByProjectView is a view of the count of calls, by method and by
+	// the project the request targets, taken from the project field of the
+	// request.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CallsByProjectView = &view.View{
+		Measure:     CallsByProject,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{tagKeyMethod, tagKeyProject},
+	}
+)
+
+var (
+	// CallsTotal is a measure of the calls made by disks clients, recorded
+	// once per call when it returns, however many times it was retried.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CallsTotal = stats.Int64(
+		statsPrefix+"calls_total",
+		"Number of calls made, by method and outcome",
+		stats.UnitDimensionless,
+	)
+
+	// CallsTotalView is a view of the count of calls, by method, by whether
+	// the call failed, with the error tag set to "true" or "false", and by
+	// project and zone if DisksClientConfig.LocationMetricTags is set. The
+	// error rate of a method is the ratio of its calls with error "true" to
+	// all of its calls.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CallsTotalView = &view.View{
+		Measure:     CallsTotal,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{tagKeyMethod, tagKeyError, tagKeyProject, tagKeyZone},
+	}
+)
+
+var (
+	// DecodeDuration is a measure of how long decoding the JSON body of a
+	// successful response took, in microseconds. Compared with the time a
+	// call takes, it tells the time spent parsing responses, such as those of
+	// large aggregated lists, from the time spent on the network.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	DecodeDuration = stats.Int64(
+		statsPrefix+"decode_duration",
+		"Time spent decoding response bodies",
+		"us",
+	)
+
+	// DecodeDurationView is a view of the distribution of DecodeDuration
+	// values, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	DecodeDurationView = &view.View{
+		Measure: DecodeDuration,
+		Aggregation: view.Distribution(0.0, 100.0, 250.0, 500.0, 1000.0, 2500.0, 5000.0, 10000.0, 25000.0, 50000.0,
+			100000.0, 250000.0, 500000.0, 1000000.0),
+		TagKeys: []tag.Key{tagKeyMethod},
+	}
+)
+
+var (
+	// SuccessfulAttempt is a measure of the number of the attempt that
+	// succeeded, recorded for each successful call: 1 if the call succeeded
+	// without being retried, 2 if it succeeded on its first retry, and so on.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SuccessfulAttempt = stats.Int64(
+		statsPrefix+"successful_attempt",
+		"Number of the attempt on which a call succeeded",
+		stats.UnitDimensionless,
+	)
+
+	// SuccessfulAttemptView is a view of the distribution of
+	// SuccessfulAttempt values, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SuccessfulAttemptView = &view.View{
+		Measure:     SuccessfulAttempt,
+		Aggregation: view.Distribution(1.0, 2.0, 3.0, 4.0, 5.0, 10.0, 20.0),
+		TagKeys:     []tag.Key{tagKeyMethod},
+	}
+)
+
+var (
+	// AggregatedScopesTotal is a measure of the number of scopes, such as
+	// zones, returned by each page of an AggregatedList call.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AggregatedScopesTotal = stats.Int64(
+		statsPrefix+"aggregated_scopes_total",
+		"Number of scopes returned by aggregated lists",
+		stats.UnitDimensionless,
+	)
+
+	// AggregatedScopesUnreachable is a measure of the number of scopes
+	// reported as unreachable by each page of an AggregatedList call.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AggregatedScopesUnreachable = stats.Int64(
+		statsPrefix+"aggregated_scopes_unreachable",
+		"Number of scopes reported unreachable by aggregated lists",
+		stats.UnitDimensionless,
+	)
+
+	// AggregatedScopesTotalView is a view of the total number of scopes
+	// returned by aggregated lists, by project.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AggregatedScopesTotalView = &view.View{
+		Measure:     AggregatedScopesTotal,
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{tagKeyProject},
+	}
+
+	// AggregatedScopesUnreachableView is a view of the total number of
+	// scopes reported unreachable by aggregated lists, by project.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AggregatedScopesUnreachableView = &view.View{
+		Measure:     AggregatedScopesUnreachable,
+		Aggregation: view.Sum(),
+		TagKeys:     []tag.Key{tagKeyProject},
+	}
+)
+
+var (
+	// TokenRemainingLifetime is a measure of the remaining lifetime of the
+	// access token a request is sent with, in milliseconds, recorded for
+	// each request when DisksClientConfig.TokenLifetimeMetrics is set. A
+	// value that is often near zero means that requests often wait for the
+	// token to be refreshed, which ProactiveTokenRefresh avoids.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TokenRemainingLifetime = stats.Int64(
+		statsPrefix+"token_remaining_lifetime",
+		"Remaining lifetime of the access token requests are sent with",
+		stats.UnitMilliseconds,
+	)
+
+	// TokenRemainingLifetimeView is a view of the last recorded
+	// TokenRemainingLifetime value, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TokenRemainingLifetimeView = &view.View{
+		Measure:     TokenRemainingLifetime,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{tagKeyMethod},
+	}
+)
+
+// EnableInFlightRequestsView enables the InFlightRequests metric.
+func EnableInFlightRequestsView() error {
+	return enableViews(InFlightRequestsView)
+}
+
+// DisableInFlightRequestsView disables the InFlightRequests metric.
+func DisableInFlightRequestsView() {
+	disableViews(InFlightRequestsView)
+}
+
+// EnableOperationWaitViews enables the OperationWaitDuration and
+// OperationPollCount metrics.
+func EnableOperationWaitViews() error {
+	return enableViews(OperationWaitDurationView, OperationPollCountView)
+}
+
+// DisableOperationWaitViews disables the OperationWaitDuration and
+// OperationPollCount metrics.
+func DisableOperationWaitViews() {
+	disableViews(OperationWaitDurationView, OperationPollCountView)
+}
+
+// EnableMissingDeadlineCountView enables the MissingDeadlineCount metric.
+func EnableMissingDeadlineCountView() error {
+	return enableViews(MissingDeadlineCountView)
+}
+
+// DisableMissingDeadlineCountView disables the MissingDeadlineCount metric.
+func DisableMissingDeadlineCountView() {
+	disableViews(MissingDeadlineCountView)
+}
+
+// EnableCircuitBreakerStateView enables the CircuitBreakerState metric.
+func EnableCircuitBreakerStateView() error {
+	return enableViews(CircuitBreakerStateView)
+}
+
+// DisableCircuitBreakerStateView disables the CircuitBreakerState metric.
+func DisableCircuitBreakerStateView() {
+	disableViews(CircuitBreakerStateView)
+}
+
+// EnableCallsByProjectView enables the CallsByProject metric.
+func EnableCallsByProjectView() error {
+	return enableViews(CallsByProjectView)
+}
+
+// DisableCallsByProjectView disables the CallsByProject metric.
+func DisableCallsByProjectView() {
+	disableViews(CallsByProjectView)
+}
+
+// EnableCallsTotalView enables the CallsTotal metric.
+func EnableCallsTotalView() error {
+	return enableViews(CallsTotalView)
+}
+
+// DisableCallsTotalView disables the CallsTotal metric.
+func DisableCallsTotalView() {
+	disableViews(CallsTotalView)
+}
+
+// EnableSuccessfulAttemptView enables the SuccessfulAttempt metric.
+func EnableSuccessfulAttemptView() error {
+	return enableViews(SuccessfulAttemptView)
+}
+
+// DisableSuccessfulAttemptView disables the SuccessfulAttempt metric.
+func DisableSuccessfulAttemptView() {
+	disableViews(SuccessfulAttemptView)
+}
+
+// EnableDecodeDurationView enables the DecodeDuration metric.
+func EnableDecodeDurationView() error {
+	return enableViews(DecodeDurationView)
+}
+
+// DisableDecodeDurationView disables the DecodeDuration metric.
+func DisableDecodeDurationView() {
+	disableViews(DecodeDurationView)
+}
+
+// EnableAggregatedScopesViews enables the AggregatedScopesTotal and
+// AggregatedScopesUnreachable metrics.
+func EnableAggregatedScopesViews() error {
+	return enableViews(AggregatedScopesTotalView, AggregatedScopesUnreachableView)
+}
+
+// DisableAggregatedScopesViews disables the AggregatedScopesTotal and
+// AggregatedScopesUnreachable metrics.
+func DisableAggregatedScopesViews() {
+	disableViews(AggregatedScopesTotalView, AggregatedScopesUnreachableView)
+}
+
+// EnableTokenRemainingLifetimeView enables the TokenRemainingLifetime
+// metric.
+func EnableTokenRemainingLifetimeView() error {
+	return enableViews(TokenRemainingLifetimeView)
+}
+
+// DisableTokenRemainingLifetimeView disables the TokenRemainingLifetime
+// metric.
+func DisableTokenRemainingLifetimeView() {
+	disableViews(TokenRemainingLifetimeView)
+}
+
+func enableViews(views ...*view.View) error {
+	if err := view.Register(views...); err != nil {
+		return err
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	for _, v := range views {
+		enabledViews[v] = true
+	}
+	return nil
+}
+
+func disableViews(views ...*view.View) {
+	statsMu.Lock()
+	for _, v := range views {
+		delete(enabledViews, v)
+	}
+	statsMu.Unlock()
+	view.Unregister(views...)
+}
+
+func isViewEnabled(v *view.View) bool {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	return enabledViews[v]
+}
+
+// recordStat records n for m under the given method tag and any other given
+// tags. Callers check isViewEnabled first so that nothing is recorded for
+// disabled views.
+func recordStat(ctx context.Context, m *stats.Int64Measure, method string, n int64, tags ...tag.Mutator) {
+	mutators := append([]tag.Mutator{tag.Upsert(tagKeyMethod, method)}, tags...)
+	stats.RecordWithTags(ctx, mutators, m.M(n))
+}
+
+// recordOperationWait records the duration of a wait for an operation of the
+// given type, and the number of polls it took, with any other given tags.
+func recordOperationWait(ctx context.Context, opType string, d time.Duration, polls int, tags ...tag.Mutator) {
+	mutators := append([]tag.Mutator{tag.Upsert(tagKeyOperationType, opType)}, tags...)
+	if isViewEnabled(OperationWaitDurationView) {
+		stats.RecordWithTags(ctx, mutators, OperationWaitDuration.M(int64(d/time.Millisecond)))
+	}
+	if isViewEnabled(OperationPollCountView) {
+		stats.RecordWithTags(ctx, mutators, OperationPollCount.M(int64(polls)))
+	}
+}
+
+// recordAggregatedScopes records the number of scopes returned by a page of
+// an aggregated list of the given project, and how many of them were
+// unreachable.
+func recordAggregatedScopes(ctx context.Context, project string, total, unreachable int) {
+	mutators := []tag.Mutator{tag.Upsert(tagKeyProject, project)}
+	if isViewEnabled(AggregatedScopesTotalView) {
+		stats.RecordWithTags(ctx, mutators, AggregatedScopesTotal.M(int64(total)))
+	}
+	if isViewEnabled(AggregatedScopesUnreachableView) {
+		stats.RecordWithTags(ctx, mutators, AggregatedScopesUnreachable.M(int64(unreachable)))
+	}
+}
+
+// locationTags returns the project and zone tags to record with the metrics
+// of a request to the given project and zone, or nil if
+// DisksClientConfig.LocationMetricTags is not set. The zone tag is omitted if
+// zone is empty, as it is for aggregated lists.
+func (c *disksRESTClient) locationTags(project, zone string) []tag.Mutator {
+	if !c.config.LocationMetricTags {
+		return nil
+	}
+	tags := []tag.Mutator{tag.Upsert(tagKeyProject, project)}
+	if zone != "" {
+		tags = append(tags, tag.Upsert(tagKeyZone, zone))
+	}
+	return tags
+}
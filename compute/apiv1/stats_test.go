@@ -0,0 +1,343 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	gax "github.com/googleapis/gax-go/v2"
+	"go.opencensus.io/stats/view"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// viewSum returns the total of the Sum aggregations of all rows of v.
+func viewSum(t *testing.T, v *view.View) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum float64
+	for _, row := range rows {
+		sum += row.Data.(*view.SumData).Value
+	}
+	return sum
+}
+
+func TestInFlightRequests(t *testing.T) {
+	if err := EnableInFlightRequestsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableInFlightRequestsView()
+
+	const n = 3
+	release := make(chan struct{})
+	var mu sync.Mutex
+	unavailable := 0
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{clock: newFakeClock()}, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/held"):
+			<-release
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+			return
+		case strings.HasSuffix(r.URL.Path, "/flaky"):
+			mu.Lock()
+			unavailable++
+			retry := unavailable == 1
+			mu.Unlock()
+			if retry {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+	get := func(disk string) error {
+		_, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: disk})
+		return err
+	}
+	waitForSum := func(want float64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for viewSum(t, InFlightRequestsView) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %v requests in flight, want %v", viewSum(t, InFlightRequestsView), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := get("held"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitForSum(n)
+	close(release)
+	wg.Wait()
+	if got := viewSum(t, InFlightRequestsView); got != 0 {
+		t.Errorf("got %v requests in flight after they succeeded, want 0", got)
+	}
+
+	if err := get("missing"); err == nil {
+		t.Fatal("got nil error, want the error of the request")
+	}
+	if got := viewSum(t, InFlightRequestsView); got != 0 {
+		t.Errorf("got %v requests in flight after a request failed, want 0", got)
+	}
+
+	if err := get("flaky"); err != nil {
+		t.Fatal(err)
+	}
+	if unavailable != 2 {
+		t.Fatalf("got %d attempts, want the request to be retried once", unavailable)
+	}
+	if got := viewSum(t, InFlightRequestsView); got != 0 {
+		t.Errorf("got %v requests in flight after a retried request, want 0", got)
+	}
+}
+
+func TestSuccessfulAttemptStats(t *testing.T) {
+	if err := EnableSuccessfulAttemptView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSuccessfulAttemptView()
+
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"},
+		gax.WithRetry(func() gax.Retryer { return fixedRetryer(0) })); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(SuccessfulAttemptView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Tags[0].Value != "compute.disks.get" {
+		t.Fatalf("got rows %v, want a single row for compute.disks.get", rows)
+	}
+	if data := rows[0].Data.(*view.DistributionData); data.Count != 1 || data.Max != 3 {
+		t.Errorf("got %d calls succeeding on attempt %v, want 1 call succeeding on attempt 3", data.Count, data.Max)
+	}
+}
+
+func TestDecodeDurationStats(t *testing.T) {
+	if err := EnableDecodeDurationView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableDecodeDurationView()
+
+	var calls int
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"name": "d"}`))
+	})
+	defer teardown()
+
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"},
+		gax.WithRetry(func() gax.Retryer { return fixedRetryer(0) })); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(DecodeDurationView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Tags[0].Value != "compute.disks.get" {
+		t.Fatalf("got rows %v, want a single row for compute.disks.get", rows)
+	}
+	if data := rows[0].Data.(*view.DistributionData); data.Count != 1 {
+		t.Errorf("got %d decoded responses, want only the successful response to be decoded", data.Count)
+	}
+}
+
+func TestCallsByProjectStats(t *testing.T) {
+	if err := EnableCallsByProjectView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableCallsByProjectView()
+
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+	for _, project := range []string{"p1", "p1", "p2"} {
+		if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: project, Zone: "z", Disk: "d"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := view.RetrieveData(CallsByProjectView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		var method, project string
+		for _, tg := range row.Tags {
+			switch tg.Key {
+			case tagKeyMethod:
+				method = tg.Value
+			case tagKeyProject:
+				project = tg.Value
+			}
+		}
+		got[method+" "+project] += row.Data.(*view.CountData).Value
+	}
+	want := map[string]int64{"compute.disks.get p1": 2, "compute.disks.get p2": 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCallsTotalStats(t *testing.T) {
+	if err := EnableCallsTotalView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableCallsTotalView()
+
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	ctx := context.Background()
+	for _, disk := range []string{"d", "d", "missing"} {
+		c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: disk})
+	}
+
+	rows, err := view.RetrieveData(CallsTotalView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		var method, failed string
+		for _, tg := range row.Tags {
+			switch tg.Key {
+			case tagKeyMethod:
+				method = tg.Value
+			case tagKeyError:
+				failed = tg.Value
+			}
+		}
+		got[method+" error="+failed] += row.Data.(*view.CountData).Value
+	}
+	want := map[string]int64{"compute.disks.get error=false": 2, "compute.disks.get error=true": 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("calls mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLocationMetricTags(t *testing.T) {
+	if err := EnableInFlightRequestsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableInFlightRequestsView()
+
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{LocationMetricTags: true}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	defer teardown()
+	if _, err := c.Get(context.Background(), &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(InFlightRequestsView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	got := make(map[string]string)
+	for _, tg := range rows[0].Tags {
+		got[tg.Key.Name()] = tg.Value
+	}
+	want := map[string]string{"method": "compute.disks.get", "project": "p", "zone": "z"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("tags mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAggregatedScopesStats(t *testing.T) {
+	if err := EnableAggregatedScopesViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableAggregatedScopesViews()
+
+	c, teardown := newFakeDisksClient(t, DisksClientConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"items": {"zones/a": {"disks": [{"name": "d"}]}, "zones/b": {}},
+			"unreachables": ["zones/c"]
+		}`))
+	})
+	defer teardown()
+
+	it := c.AggregatedList(context.Background(), &computepb.AggregatedListDisksRequest{Project: "p"})
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		v    *view.View
+		want float64
+	}{
+		{AggregatedScopesTotalView, 2},
+		{AggregatedScopesUnreachableView, 1},
+	} {
+		rows, err := view.RetrieveData(test.v.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 || len(rows[0].Tags) != 1 || rows[0].Tags[0].Value != "p" {
+			t.Fatalf("%s: got rows %v, want a single row for project p", test.v.Name, rows)
+		}
+		if got := rows[0].Data.(*view.SumData).Value; got != test.want {
+			t.Errorf("%s = %v, want %v", test.v.Name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// rpcKey is the context key of the RPC name of a request, set by do for the
+// tokenLifetimeTransport when DisksClientConfig.TokenLifetimeMetrics is set.
+type rpcKey struct{}
+
+// lastTokenSource is a token source that remembers the last token returned
+// by src, so that the expiry of the token a request is sent with can be
+// found from its Authorization header.
+type lastTokenSource struct {
+	src oauth2.TokenSource
+
+	mu  sync.Mutex
+	tok *oauth2.Token
+}
+
+func (ts *lastTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	ts.mu.Lock()
+	ts.tok = tok
+	ts.mu.Unlock()
+	return tok, nil
+}
+
+// expiry returns the expiry of the token sent in the given Authorization
+// header, or false if it is not the last token of the source, or never
+// expires.
+func (ts *lastTokenSource) expiry(authorization string) (time.Time, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.tok == nil || ts.tok.Expiry.IsZero() || authorization != ts.tok.Type()+" "+ts.tok.AccessToken {
+		return time.Time{}, false
+	}
+	return ts.tok.Expiry, true
+}
+
+// tokenLifetimeTransport records the remaining lifetime of the token of each
+// request it sends, once the oauth2.Transport it is the base of has set the
+// Authorization header.
+type tokenLifetimeTransport struct {
+	base http.RoundTripper
+	src  *lastTokenSource
+	now  func() time.Time
+}
+
+func (t *tokenLifetimeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isViewEnabled(TokenRemainingLifetimeView) {
+		rpc, ok := req.Context().Value(rpcKey{}).(string)
+		if expiry, found := t.src.expiry(req.Header.Get("Authorization")); ok && found {
+			recordStat(req.Context(), TokenRemainingLifetime, rpc, int64(expiry.Sub(t.now())/time.Millisecond))
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// tokenLifetimeClient returns a copy of hc that records the remaining
+// lifetime of its tokens, as configured by TokenLifetimeMetrics, or hc itself
+// if it is not enabled or hc does not authenticate with an OAuth2 token
+// source.
+func (cfg DisksClientConfig) tokenLifetimeClient(hc *http.Client) *http.Client {
+	if !cfg.TokenLifetimeMetrics {
+		return hc
+	}
+	t, ok := hc.Transport.(*oauth2.Transport)
+	if !ok {
+		return hc
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	src := &lastTokenSource{src: t.Source}
+	// Copy the client and its transport rather than modify them, as in
+	// proactiveRefreshClient.
+	trans := *t
+	trans.Source = src
+	trans.Base = &tokenLifetimeTransport{base: base, src: src, now: cfg.systemClock().Now}
+	c := *hc
+	c.Transport = &trans
+	return &c
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"google.golang.org/api/option"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+func TestTokenLifetimeMetrics(t *testing.T) {
+	if err := EnableTokenRemainingLifetimeView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableTokenRemainingLifetimeView()
+
+	var got []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"name": "d"}`))
+	}))
+	defer svr.Close()
+	ctx := context.Background()
+	req := &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}
+
+	get := func(config DisksClientConfig) {
+		t.Helper()
+		src := &countingTokenSource{now: time.Now}
+		c, err := NewDisksRESTClientWithConfig(ctx, config, option.WithEndpoint(svr.URL), option.WithTokenSource(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := c.Get(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get(DisksClientConfig{})
+	rows, err := view.RetrieveData(TokenRemainingLifetimeView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("got rows %v, want none without TokenLifetimeMetrics", rows)
+	}
+
+	get(DisksClientConfig{TokenLifetimeMetrics: true, ProactiveTokenRefresh: time.Minute})
+	if len(got) != 2 || got[1] != "Bearer token-1" {
+		t.Errorf("got Authorization headers %q, want the token of the token source", got)
+	}
+	rows, err = view.RetrieveData(TokenRemainingLifetimeView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Tags[0].Value != "compute.disks.get" {
+		t.Fatalf("got rows %v, want a single row for compute.disks.get", rows)
+	}
+	// The token of countingTokenSource expires an hour after it was fetched.
+	if v := rows[0].Data.(*view.LastValueData).Value; v <= float64((time.Hour-time.Minute)/time.Millisecond) || v > float64(time.Hour/time.Millisecond) {
+		t.Errorf("got a remaining lifetime of %vms, want about an hour", v)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// minTokenLifetime is the shortest remaining lifetime of a token that is
+	// still used while a new one is fetched in the background. A token that
+	// expires sooner is refreshed synchronously.
+	minTokenLifetime = 2 * time.Second
+
+	// tokenRefreshRetryInterval is the time to wait before another background
+	// refresh when a refresh failed or did not return a newer token.
+	tokenRefreshRetryInterval = time.Second
+)
+
+// proactiveTokenSource is a token source that caches the tokens of src, and
+// fetches a new token in the background once the cached one expires within
+// window, so that requests do not wait for the refresh.
+type proactiveTokenSource struct {
+	src    oauth2.TokenSource
+	window time.Duration
+	logf   func(format string, v ...interface{})
+	now    func() time.Time
+
+	mu          sync.Mutex
+	tok         *oauth2.Token
+	refreshing  bool
+	nextRefresh time.Time
+}
+
+func newProactiveTokenSource(src oauth2.TokenSource, window time.Duration, logf func(string, ...interface{})) *proactiveTokenSource {
+	return &proactiveTokenSource{
+		src:    src,
+		window: window,
+		logf:   logf,
+		now:    time.Now,
+	}
+}
+
+// Token returns the cached token, and starts a background refresh if it
+// expires within the refresh window. It only waits for a new token if there
+// is no cached token, or if the cached one is about to expire.
+func (ts *proactiveTokenSource) Token() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	now := ts.now()
+	if ts.tok != nil {
+		if ts.tok.Expiry.IsZero() || ts.tok.Expiry.Sub(now) > ts.window {
+			return ts.tok, nil
+		}
+		if ts.tok.Expiry.Sub(now) > minTokenLifetime {
+			if !ts.refreshing && !now.Before(ts.nextRefresh) {
+				ts.refreshing = true
+				go ts.refresh()
+			}
+			return ts.tok, nil
+		}
+	}
+	tok, err := ts.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	ts.tok = tok
+	return tok, nil
+}
+
+// refresh fetches a new token in the background and caches it if it expires
+// later than the cached one.
+func (ts *proactiveTokenSource) refresh() {
+	tok, err := ts.src.Token()
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.refreshing = false
+	if err != nil {
+		ts.logf("compute: refreshing the access token in the background: %v", err)
+	}
+	if err != nil || !tok.Expiry.After(ts.tok.Expiry) {
+		// src may cache its tokens, and return the same token until
+		// shortly before it expires.
+		ts.nextRefresh = ts.now().Add(tokenRefreshRetryInterval)
+		return
+	}
+	ts.tok = tok
+}
+
+// proactiveRefreshClient returns a copy of hc that refreshes its tokens
+// proactively, as configured by ProactiveTokenRefresh, or hc itself if it is
+// not enabled or hc does not authenticate with an OAuth2 token source.
+func (cfg DisksClientConfig) proactiveRefreshClient(hc *http.Client) *http.Client {
+	if cfg.ProactiveTokenRefresh <= 0 {
+		return hc
+	}
+	t, ok := hc.Transport.(*oauth2.Transport)
+	if !ok {
+		return hc
+	}
+	// Copy the client and its transport, which may have been passed by the
+	// caller with option.WithHTTPClient, rather than modify them.
+	trans := *t
+	trans.Source = newProactiveTokenSource(t.Source, cfg.ProactiveTokenRefresh, cfg.logf)
+	c := *hc
+	c.Transport = &trans
+	return &c
+}
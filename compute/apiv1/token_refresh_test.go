@@ -0,0 +1,126 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compute
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	computepb "google.golang.org/genproto/googleapis/cloud/compute/v1"
+)
+
+// countingTokenSource returns a new token, valid for an hour from now(), on
+// each call. Each call blocks until a value is received from release, if it
+// is set.
+type countingTokenSource struct {
+	now     func() time.Time
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *countingTokenSource) Token() (*oauth2.Token, error) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", s.calls), Expiry: s.now().Add(time.Hour)}, nil
+}
+
+func TestProactiveTokenSource(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	src := &countingTokenSource{now: clock}
+	ts := newProactiveTokenSource(src, 5*time.Minute, t.Logf)
+	ts.now = clock
+
+	token := func() string {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok.AccessToken
+	}
+	if got := token(); got != "token-1" {
+		t.Fatalf("got %s, want token-1 fetched synchronously", got)
+	}
+	now = now.Add(50 * time.Minute)
+	if got := token(); got != "token-1" {
+		t.Fatalf("got %s, want the cached token-1 outside the refresh window", got)
+	}
+
+	// Within the window, the cached token is returned while a new one is
+	// fetched in the background.
+	src.release = make(chan struct{})
+	now = now.Add(6 * time.Minute)
+	if got := token(); got != "token-1" {
+		t.Fatalf("got %s, want the cached token-1 during the refresh", got)
+	}
+	if got := token(); got != "token-1" {
+		t.Fatalf("got %s, want the cached token-1 during the refresh", got)
+	}
+	src.release <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for token() != "token-2" {
+		if time.Now().After(deadline) {
+			t.Fatal("the token was not refreshed in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if src.calls != 2 {
+		t.Errorf("got %d calls of the token source, want a single background refresh", src.calls)
+	}
+
+	// A token about to expire is refreshed synchronously.
+	src.release = nil
+	now = now.Add(time.Hour - time.Second)
+	if got := token(); got != "token-3" {
+		t.Errorf("got %s, want token-3 fetched synchronously", got)
+	}
+}
+
+func TestProactiveTokenRefresh(t *testing.T) {
+	var got []string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"name": "d"}`))
+	}))
+	defer svr.Close()
+
+	src := &countingTokenSource{now: time.Now}
+	ctx := context.Background()
+	c, err := NewDisksRESTClientWithConfig(ctx, DisksClientConfig{ProactiveTokenRefresh: time.Minute},
+		option.WithEndpoint(svr.URL), option.WithTokenSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Get(ctx, &computepb.GetDiskRequest{Project: "p", Zone: "z", Disk: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "Bearer token-1" {
+		t.Errorf("got Authorization headers %q, want the token of the token source", got)
+	}
+}
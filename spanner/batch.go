@@ -143,7 +143,7 @@ func (t *BatchReadOnlyTransaction) PartitionReadUsingIndexWithOptions(ctx contex
 		PartitionOptions: opt.toProto(),
 	}, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+	if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "PartitionReadUsingIndexWithOptions"); err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 		}
@@ -158,6 +158,9 @@ func (t *BatchReadOnlyTransaction) PartitionReadUsingIndexWithOptions(ctx contex
 		KeySet:         kset,
 		RequestOptions: createRequestOptions(readOptions.Priority, readOptions.RequestTag, ""),
 	}
+	if err == nil {
+		recordPartitionCount(ctx, t.ct, "PartitionReadUsingIndexWithOptions", len(resp.GetPartitions()))
+	}
 	// Generate partitions.
 	for _, p := range resp.GetPartitions() {
 		partitions = append(partitions, &Partition{
@@ -204,7 +207,7 @@ func (t *BatchReadOnlyTransaction) partitionQuery(ctx context.Context, statement
 	}
 	resp, err := client.PartitionQuery(contextWithOutgoingMetadata(ctx, sh.getMetadata()), req, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+	if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "partitionQuery"); err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 		}
@@ -221,6 +224,10 @@ func (t *BatchReadOnlyTransaction) partitionQuery(ctx context.Context, statement
 		RequestOptions: createRequestOptions(qOpts.Priority, qOpts.RequestTag, ""),
 	}
 
+	if err == nil {
+		recordPartitionCount(ctx, t.ct, "partitionQuery", len(resp.GetPartitions()))
+	}
+
 	// generate Partitions
 	var partitions []*Partition
 	for _, p := range resp.GetPartitions() {
@@ -273,7 +280,7 @@ func (t *BatchReadOnlyTransaction) Cleanup(ctx context.Context) {
 	var md metadata.MD
 	err := client.DeleteSession(contextWithOutgoingMetadata(ctx, sh.getMetadata()), &sppb.DeleteSessionRequest{Name: sid}, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+	if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "Cleanup"); err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 		}
@@ -322,7 +329,7 @@ func (t *BatchReadOnlyTransaction) Execute(ctx context.Context, p *Partition) *R
 				return client, err
 			}
 			md, err := client.Header()
-			if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+			if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 				if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "Execute"); err != nil {
 					trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 				}
@@ -347,7 +354,7 @@ func (t *BatchReadOnlyTransaction) Execute(ctx context.Context, p *Partition) *R
 			}
 			md, err := client.Header()
 
-			if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+			if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 				if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "Execute"); err != nil {
 					trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 				}
@@ -355,12 +362,14 @@ func (t *BatchReadOnlyTransaction) Execute(ctx context.Context, p *Partition) *R
 			return client, err
 		}
 	}
-	return stream(
+	ri := stream(
 		contextWithOutgoingMetadata(ctx, sh.getMetadata()),
 		sh.session.logger,
 		rpc,
 		t.setTimestamp,
 		t.release)
+	ri.ct, ri.method = t.ct, "Execute"
+	return ri
 }
 
 // MarshalBinary implements BinaryMarshaler.
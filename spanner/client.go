@@ -26,6 +26,7 @@ import (
 
 	"cloud.google.com/go/internal/trace"
 	vkit "cloud.google.com/go/spanner/apiv1"
+	"go.opencensus.io/tag"
 	"google.golang.org/api/option"
 	"google.golang.org/api/option/internaloption"
 	gtransport "google.golang.org/api/transport/grpc"
@@ -253,6 +254,73 @@ func (c *Client) Close() {
 	c.sc.close()
 }
 
+// EnableGfeLatencyMetrics starts recording the GFELatency and
+// GFEHeaderMissingCount metrics for the RPCs of this client only. The
+// GFELatencyView and GFEHeaderMissingCountView views must be registered for
+// the data to be exported.
+//
+// EnableGfeLatencyView, EnableGfeHeaderMissingCountView,
+// EnableGfeLatencyAndHeaderMissingCountViews and
+// DisableGfeLatencyAndHeaderMissingCountViews enable or disable GFE metrics
+// for all clients, overriding the setting made by this method.
+func (c *Client) EnableGfeLatencyMetrics() {
+	c.sc.setGFELatencyMetricsEnabled(true)
+}
+
+// DisableGfeLatencyMetrics stops recording the GFELatency and
+// GFEHeaderMissingCount metrics for the RPCs of this client.
+func (c *Client) DisableGfeLatencyMetrics() {
+	c.sc.setGFELatencyMetricsEnabled(false)
+}
+
+// RecordSessionPoolEvent records n occurrences of an event of the given type
+// in the SessionPoolEvents metric, tagged with the common tags of the client,
+// such as its client ID and database. It lets applications and libraries
+// built on the client report their own session pool events, such as
+// "prewarm" or "evict", next to the metrics of the pool.
+//
+// Event types are free-form, but each distinct type adds a time series per
+// client, so they should come from a small fixed set, and never hold values
+// such as session or request IDs. An event type must be a non-empty string of
+// printable ASCII characters, at most 255 characters long.
+//
+// Nothing is recorded unless SessionPoolEventsView is enabled, for example
+// with EnableSessionPoolEventsView.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func (c *Client) RecordSessionPoolEvent(ctx context.Context, eventType string, n int64) error {
+	if eventType == "" {
+		return spannerErrorf(codes.InvalidArgument, "session pool event type must not be empty")
+	}
+	t := tag.Tag{Key: tagKeyEventType, Value: eventType}
+	if _, err := tag.New(ctx, tag.Upsert(t.Key, t.Value)); err != nil {
+		return spannerErrorf(codes.InvalidArgument, "invalid session pool event type %q: %v", eventType, err)
+	}
+	if !isViewEnabled(SessionPoolEventsView) {
+		return nil
+	}
+	c.idleSessions.recordStat(ctx, SessionPoolEvents, n, t)
+	return nil
+}
+
+// ResetMaxQueryLatency starts tracking the highest query latency of the
+// client anew, and records MaxQueryLatency as 0, if its view is enabled, for
+// example with EnableMaxQueryLatencyView, until a query completes.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func (c *Client) ResetMaxQueryLatency() {
+	c.sc.maxQueryLatencyMu.Lock()
+	c.sc.maxQueryLatency = -1
+	c.sc.maxQueryLatencyMu.Unlock()
+	if c.ct == nil || !isViewEnabled(MaxQueryLatencyView) {
+		return
+	}
+	ctx, err := contextWithCommonTags(context.Background(), c.ct, "query")
+	if err != nil {
+		logf(c.logger, "Failed to tag metrics, error: %v", err)
+		return
+	}
+	recordStat(ctx, MaxQueryLatency, 0)
+}
+
 // Single provides a read-only snapshot transaction optimized for the case
 // where only a single read or query is needed.  This is more efficient than
 // using ReadOnlyTransaction() for a single read or query.
@@ -457,18 +525,22 @@ func (c *Client) rwTransaction(ctx context.Context, f func(context.Context, *Rea
 		return resp, err
 	}
 	var (
-		sh *sessionHandle
+		sh       *sessionHandle
+		attempts int
+		start    = time.Now()
 	)
 	defer func() {
 		if sh != nil {
 			sh.recycle()
 		}
+		recordTransactionDuration(ctx, c.ct, time.Since(start), err)
 	}()
 	err = runWithRetryOnAbortedOrSessionNotFound(ctx, func(ctx context.Context) error {
 		var (
 			err error
 			t   *ReadWriteTransaction
 		)
+		attempts++
 		if sh == nil || sh.getID() == "" || sh.getClient() == nil {
 			// Session handle hasn't been allocated or has been destroyed.
 			sh, err = c.idleSessions.takeWriteSession(ctx)
@@ -496,6 +568,9 @@ func (c *Client) rwTransaction(ctx context.Context, f func(context.Context, *Rea
 		resp, err = t.runInTransaction(ctx, f)
 		return err
 	})
+	if err == nil {
+		recordCommitAttempts(ctx, c.ct, attempts)
+	}
 	return resp, err
 }
 
@@ -369,6 +369,14 @@ This client has been instrumented to use OpenCensus tracing
 (http://opencensus.io). To enable tracing, see "Enabling Tracing for a Program"
 at https://godoc.org/go.opencensus.io/trace. OpenCensus tracing requires Go 1.8
 or higher.
+
+The OpenCensus metrics of the client, such as GetSessionTimeoutsCount and
+GFELatency, are recorded with the tags of the context passed to the method
+that caused them, in addition to the client's own tags. Tags of the
+application, such as a service name added with tag.New, therefore also
+appear on the recorded rows if the views include their keys. Metrics
+recorded in the background, such as the number of open sessions, only carry
+the client's own tags.
 */
 package spanner // import "cloud.google.com/go/spanner"
 
@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -26,10 +28,18 @@ import (
 	"cloud.google.com/go/internal/version"
 	stestutil "cloud.google.com/go/spanner/internal/testutil"
 	structpb "github.com/golang/protobuf/ptypes/struct"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/resource"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"golang.org/x/xerrors"
 	"google.golang.org/api/iterator"
 	spannerpb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // Check that stats are being exported.
@@ -264,6 +274,121 @@ func TestOCStats_SessionPool_GetSessionTimeoutsCount(t *testing.T) {
 	}
 }
 
+func TestOCStats_GFE_EnableBothViewsOrNone(t *testing.T) {
+	// Occupy the name of GFEHeaderMissingCountView with a different view, so
+	// that it cannot be registered.
+	conflicting := &view.View{
+		Name:        GFEHeaderMissingCountView.Name,
+		Measure:     GFEHeaderMissingCount,
+		Aggregation: view.Sum(),
+	}
+	if err := view.Register(conflicting); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(conflicting)
+	defer setGFELatencyMetricsFlag(false)
+	setGFELatencyMetricsFlag(false)
+
+	if err := EnableGfeLatencyAndHeaderMissingCountViews(); err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	if v := view.Find(GFELatencyView.Name); v != nil {
+		view.Unregister(v)
+		t.Error("GFELatencyView was registered without GFEHeaderMissingCountView")
+	}
+	if getGFELatencyMetricsFlag() {
+		t.Error("GFE metrics were enabled although registering the views failed")
+	}
+}
+
+func TestOCStats_GFE_MalformedHeader(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		parse  bool
+	}{
+		{header: "gfet4t7; dur=abc", parse: true},
+		{header: "foo; dur=123"},
+	} {
+		md := metadata.Pairs("server-timing", test.header)
+		err := captureGFELatencyStats(context.Background(), md, "query")
+		if err == nil {
+			t.Fatalf("%q: expected an error, got nil", test.header)
+		}
+		for _, want := range []string{test.header, "query"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%q: error %q does not contain %q", test.header, err, want)
+			}
+		}
+		var numErr *strconv.NumError
+		if got := xerrors.As(err, &numErr); got != test.parse {
+			t.Errorf("%q: wraps a parse error: got %v, want %v", test.header, got, test.parse)
+		}
+	}
+}
+
+func TestOCStats_GFE_PerClient(t *testing.T) {
+	setGFELatencyMetricsFlag(false)
+	defer setGFELatencyMetricsFlag(false)
+
+	_, c1, teardown1 := setupMockedTestServer(t)
+	defer teardown1()
+	_, c2, teardown2 := setupMockedTestServer(t)
+	defer teardown2()
+
+	c1.EnableGfeLatencyMetrics()
+	if !c1.sc.gfeLatencyMetricsEnabled() {
+		t.Error("GFE metrics are not enabled for the first client")
+	}
+	if c2.sc.gfeLatencyMetricsEnabled() {
+		t.Error("GFE metrics are enabled for the second client")
+	}
+
+	// The package-level flag overrides the per-client setting.
+	setGFELatencyMetricsFlag(true)
+	if !c1.sc.gfeLatencyMetricsEnabled() || !c2.sc.gfeLatencyMetricsEnabled() {
+		t.Error("GFE metrics are not enabled for all clients")
+	}
+	c2.DisableGfeLatencyMetrics()
+	if !c1.sc.gfeLatencyMetricsEnabled() || c2.sc.gfeLatencyMetricsEnabled() {
+		t.Error("GFE metrics were not disabled for the second client only")
+	}
+}
+
+func TestOCStats_GFE_LatencyExemplar(t *testing.T) {
+	if err := view.Register(GFELatencyView); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(GFELatencyView)
+
+	ctx, span := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	ctx, err := tag.New(ctx, tag.Upsert(tagKeyClientID, "client-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := metadata.Pairs("server-timing", "gfet4t7; dur=123")
+	if err := captureGFELatencyStats(ctx, md, "query"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(GFELatencyView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	var found bool
+	for _, e := range rows[0].Data.(*view.DistributionData).ExemplarsPerBucket {
+		if e != nil && e.Attachments[metricdata.AttachmentKeySpanContext] == span.SpanContext() {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("no exemplar with the span context of the request was recorded")
+	}
+}
+
 func TestOCStats_GFE_Latency(t *testing.T) {
 	te := testutil.NewTestExporter([]*view.View{GFELatencyView, GFEHeaderMissingCountView}...)
 	defer te.Unregister()
@@ -373,3 +498,1068 @@ func checkCommonTags(t *testing.T, m map[tag.Key]string) {
 		t.Fatalf("Incorrect library version: %v", m[tagKeyLibVersion])
 	}
 }
+
+func TestOCStats_SessionPool_HitRatio(t *testing.T) {
+	if err := EnableSessionPoolHitRatioViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionPoolHitRatioViews()
+
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 0}})
+	defer teardown()
+
+	ctx := context.Background()
+	// The pool is empty, so the first read has to wait for a new session.
+	client.Single().ReadRow(ctx, "Users", Key{"alice"}, []string{"email"})
+	// The session of the first read is back in the pool for the second.
+	client.Single().ReadRow(ctx, "Users", Key{"alice"}, []string{"email"})
+	if got, want := viewCount(t, SessionPoolMissCountView), int64(1); got != want {
+		t.Errorf("miss count mismatch\nGot: %d\nWant: %d", got, want)
+	}
+	if got, want := viewCount(t, SessionPoolHitCountView), int64(1); got != want {
+		t.Errorf("hit count mismatch\nGot: %d\nWant: %d", got, want)
+	}
+}
+
+func TestOCStats_SessionPool_HoldDuration(t *testing.T) {
+	if err := EnableSessionHoldDurationView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionHoldDurationView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	client.Single().ReadRow(context.Background(), "Users", Key{"alice"}, []string{"email"})
+
+	rows, err := view.RetrieveData(SessionHoldDurationView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 {
+		t.Fatal("No metrics were exported")
+	}
+	var n int64
+	for _, row := range rows {
+		checkCommonTags(t, getTagMap(row.Tags))
+		n += row.Data.(*view.DistributionData).Count
+	}
+	if got, want := n, int64(1); got != want {
+		t.Errorf("hold duration count mismatch\nGot: %d\nWant: %d", got, want)
+	}
+}
+
+func TestOCStats_SessionPool_AcquisitionLatencyByType(t *testing.T) {
+	if err := EnableSessionAcquisitionByTypeViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionAcquisitionByTypeViews()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	ctx := context.Background()
+	client.Single().ReadRow(ctx, "Users", Key{"alice"}, []string{"email"})
+	client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return nil
+	})
+
+	rows, err := view.RetrieveData(SessionAcquisitionLatencyByTypeView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeySessionType]] += row.Data.(*view.DistributionData).Count
+	}
+	want := map[string]int64{"read_only": 1, "read_write": 1}
+	if !testEqual(got, want) {
+		t.Errorf("acquisitions by type mismatch\nGot: %v\nWant: %v", got, want)
+	}
+}
+
+func TestOCStats_BytesReturned(t *testing.T) {
+	if err := EnableBytesReturnedView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableBytesReturnedView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	iter := client.Single().Query(context.Background(), NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	if err := iter.Do(func(*Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(BytesReturnedView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	m := getTagMap(rows[0].Tags)
+	checkCommonTags(t, m)
+	if got, want := m[tagKeyMethod], "query"; got != want {
+		t.Errorf("method tag = %q, want %q", got, want)
+	}
+	if got := rows[0].Data.(*view.SumData).Value; got <= 0 {
+		t.Errorf("bytes returned = %v, want more than 0", got)
+	}
+}
+
+func TestOCStats_PartitionCount(t *testing.T) {
+	if err := EnablePartitionCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisablePartitionCountView()
+
+	ctx := context.Background()
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	txn, err := client.BatchReadOnlyTransaction(ctx, StrongRead())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Cleanup(ctx)
+	ps, err := txn.PartitionQuery(ctx, NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums), PartitionOptions{0, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(PartitionCountView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	m := getTagMap(rows[0].Tags)
+	checkCommonTags(t, m)
+	if got, want := m[tagKeyMethod], "partitionQuery"; got != want {
+		t.Errorf("method tag = %q, want %q", got, want)
+	}
+	data := rows[0].Data.(*view.DistributionData)
+	if data.Count != 1 || data.Sum() != float64(len(ps)) {
+		t.Errorf("got %d measurements summing to %v, want 1 of %d", data.Count, data.Sum(), len(ps))
+	}
+}
+
+func TestOCStats_PreservesContextTags(t *testing.T) {
+	keyService := tag.MustNewKey("service")
+	v := &view.View{
+		Name:        "test_acquired_sessions_by_service",
+		Measure:     AcquiredSessionsCount,
+		Aggregation: view.Count(),
+		TagKeys:     append(tagCommonKeys, keyService),
+	}
+	if err := view.Register(v); err != nil {
+		t.Fatal(err)
+	}
+	defer view.Unregister(v)
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	ctx, err := tag.New(context.Background(), tag.Upsert(keyService, "checkout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Single().ReadRow(ctx, "Users", Key{"alice"}, []string{"email"})
+
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		if m[keyService] == "checkout" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no row with the service tag of the context in %v", rows)
+	}
+}
+
+func TestOCStats_BatchCreateSessionsSize(t *testing.T) {
+	if err := EnableBatchCreateSessionsSizeView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableBatchCreateSessionsSizeView()
+
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 10}})
+	defer teardown()
+	waitFor(t, func() error {
+		sp := client.idleSessions
+		sp.mu.Lock()
+		defer sp.mu.Unlock()
+		if got := sp.idleList.Len(); got != 10 {
+			return fmt.Errorf("got %d idle sessions, want 10", got)
+		}
+		return nil
+	})
+
+	rows, err := view.RetrieveData(BatchCreateSessionsSizeView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeyType]] += row.Data.(*view.DistributionData).Sum()
+	}
+	want := map[string]float64{"requested": 10, "returned": 10}
+	if !testEqual(got, want) {
+		t.Errorf("batch sizes mismatch\nGot: %v\nWant: %v", got, want)
+	}
+}
+
+func TestOCStats_BatchCreateSessionsLatency(t *testing.T) {
+	if err := EnableBatchCreateSessionsLatencyView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableBatchCreateSessionsLatencyView()
+
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{NumChannels: 1, SessionPoolConfig: SessionPoolConfig{MinOpened: 10}})
+	defer teardown()
+	waitFor(t, func() error {
+		sp := client.idleSessions
+		sp.mu.Lock()
+		defer sp.mu.Unlock()
+		if got := sp.idleList.Len(); got != 10 {
+			return fmt.Errorf("got %d idle sessions, want 10", got)
+		}
+		return nil
+	})
+
+	rows, err := view.RetrieveData(BatchCreateSessionsLatencyView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	checkCommonTags(t, getTagMap(rows[0].Tags))
+	if got := rows[0].Data.(*view.DistributionData).Count; got != 1 {
+		t.Errorf("got %d BatchCreateSessions RPCs, want 1", got)
+	}
+}
+
+func TestDetectResource(t *testing.T) {
+	first := func(context.Context) (*resource.Resource, error) {
+		return &resource.Resource{Type: "k8s", Labels: map[string]string{"k8s.pod.name": "pod", "cloud.zone": "z1"}}, nil
+	}
+	second := func(context.Context) (*resource.Resource, error) {
+		return &resource.Resource{Type: "cloud", Labels: map[string]string{"cloud.zone": "z2", "host.id": "123"}}, nil
+	}
+	got, err := DetectResource(context.Background(), first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &resource.Resource{Type: "k8s", Labels: map[string]string{"k8s.pod.name": "pod", "cloud.zone": "z1", "host.id": "123"}}
+	if !testEqual(got, want) {
+		t.Errorf("got resource %v, want %v", got, want)
+	}
+}
+
+func TestOCStats_EnableViewsOnMeter(t *testing.T) {
+	meter := view.NewMeter()
+	meter.Start()
+	defer meter.Stop()
+	if err := EnableViewsOnMeter(meter, MaxAllowedSessionsCountView, SessionHoldDurationView); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableViewsOnMeter(meter, MaxAllowedSessionsCountView, SessionHoldDurationView)
+	if !isViewEnabled(SessionHoldDurationView) {
+		t.Error("opt-in view enabled on a meter is not recorded")
+	}
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	iter := client.Single().Query(context.Background(), NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	iter.Do(func(r *Row) error { return nil })
+
+	for _, v := range []*view.View{MaxAllowedSessionsCountView, SessionHoldDurationView} {
+		if view.Find(v.Name) != nil {
+			t.Errorf("%s: registered on the default meter", v.Name)
+		}
+		waitFor(t, func() error {
+			rows, err := meter.RetrieveData(v.Name)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return fmt.Errorf("%s: no data recorded to the meter", v.Name)
+			}
+			return nil
+		})
+	}
+
+	DisableViewsOnMeter(meter, MaxAllowedSessionsCountView, SessionHoldDurationView)
+	if isViewEnabled(SessionHoldDurationView) {
+		t.Error("opt-in view still recorded after DisableViewsOnMeter")
+	}
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	if len(meterViews) != 0 {
+		t.Errorf("meter still receives measurements after its views were disabled: %v", meterViews)
+	}
+}
+
+func TestOCStats_SessionKeepAlivePingCount(t *testing.T) {
+	if err := EnableSessionKeepAlivePingCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionKeepAlivePingCountView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	sh, err := client.idleSessions.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sh.recycle()
+	before := viewCount(t, SessionKeepAlivePingCountView)
+	// The mock server has no result for the ping statement, but the ping is
+	// counted when it is sent.
+	sh.session.ping()
+	waitFor(t, func() error {
+		if got := viewCount(t, SessionKeepAlivePingCountView); got <= before {
+			return fmt.Errorf("got %d pings, want more than %d", got, before)
+		}
+		return nil
+	})
+}
+
+func TestOCStats_AcquisitionQueueDepth(t *testing.T) {
+	if err := EnableAcquisitionQueueDepthView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableAcquisitionQueueDepthView()
+
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 0, MaxOpened: 1}})
+	defer teardown()
+	sp := client.idleSessions
+	sh, err := sp.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sh.recycle()
+
+	depth := func() (float64, error) {
+		rows, err := view.RetrieveData(AcquisitionQueueDepthView.Name)
+		if err != nil {
+			return 0, err
+		}
+		if len(rows) != 1 {
+			return 0, fmt.Errorf("got %d rows, want 1", len(rows))
+		}
+		checkCommonTags(t, getTagMap(rows[0].Tags))
+		return rows[0].Data.(*view.LastValueData).Value, nil
+	}
+
+	// The only session is taken, so the next caller has to wait until its
+	// context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := sp.take(ctx)
+		done <- err
+	}()
+	waitFor(t, func() error {
+		d, err := depth()
+		if err != nil {
+			return err
+		}
+		if d != 1 {
+			return fmt.Errorf("got queue depth %v, want 1", d)
+		}
+		return nil
+	})
+	cancel()
+	if err := <-done; err == nil {
+		t.Fatal("got nil error, want the session timeout error")
+	}
+	waitFor(t, func() error {
+		d, err := depth()
+		if err != nil {
+			return err
+		}
+		if d != 0 {
+			return fmt.Errorf("got queue depth %v, want 0", d)
+		}
+		return nil
+	})
+}
+
+func TestOCStats_CommitAttempts(t *testing.T) {
+	if err := EnableCommitAttemptsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableCommitAttemptsView()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	server.TestSpanner.PutExecutionTime(stestutil.MethodCommitTransaction,
+		stestutil.SimulatedExecutionTime{
+			Errors: []error{status.Error(codes.Aborted, "Transaction aborted")},
+		})
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return tx.BufferWrite([]*Mutation{Insert("Users", []string{"name"}, []interface{}{"alice"})})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(CommitAttemptsView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	checkCommonTags(t, getTagMap(rows[0].Tags))
+	data := rows[0].Data.(*view.DistributionData)
+	if data.Count != 1 || data.Max != 2 {
+		t.Errorf("got %d commits with at most %v attempts, want 1 commit with 2 attempts", data.Count, data.Max)
+	}
+}
+
+func TestOCStats_TransactionDuration(t *testing.T) {
+	if err := EnableTransactionDurationView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableTransactionDurationView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	ctx := context.Background()
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return tx.BufferWrite([]*Mutation{Insert("Users", []string{"name"}, []interface{}{"alice"})})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return status.Error(codes.FailedPrecondition, "failed")
+	}); err == nil {
+		t.Fatal("got no error from a failing transaction")
+	}
+
+	rows, err := view.RetrieveData(TransactionDurationView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeyOutcome]] += row.Data.(*view.DistributionData).Count
+	}
+	want := map[string]int64{"committed": 1, "failed": 1}
+	if !testEqual(got, want) {
+		t.Errorf("got transactions by outcome %v, want %v", got, want)
+	}
+}
+
+func TestOCStats_DMLRowsAffected(t *testing.T) {
+	if err := EnableDMLRowsAffectedView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableDMLRowsAffectedView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	ctx := context.Background()
+	stmt := NewStatement(stestutil.UpdateBarSetFoo)
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		if _, err := tx.Update(ctx, stmt); err != nil {
+			return err
+		}
+		_, err := tx.BatchUpdate(ctx, []Statement{stmt, stmt})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.PartitionedUpdate(ctx, stmt); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(DMLRowsAffectedView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeyMethod]+"/"+m[tagKeyRowCount]] += row.Data.(*view.SumData).Value
+	}
+	want := map[string]float64{
+		"Update/exact":                  stestutil.UpdateBarSetFooRowCount,
+		"BatchUpdate/exact":             2 * stestutil.UpdateBarSetFooRowCount,
+		"PartitionedUpdate/lower_bound": stestutil.UpdateBarSetFooRowCount,
+	}
+	if !testEqual(got, want) {
+		t.Errorf("got rows affected %v, want %v", got, want)
+	}
+}
+
+func TestOCStats_SlowTransactionThreshold(t *testing.T) {
+	if err := EnableTransactionDurationView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableTransactionDurationView()
+	if err := EnableTransactionCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableTransactionCountView()
+	if err := SetSlowTransactionThreshold(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	defer SetSlowTransactionThreshold(0)
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return tx.BufferWrite([]*Mutation{Insert("Users", []string{"name"}, []interface{}{"alice"})})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(TransactionCountView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	m := getTagMap(rows[0].Tags)
+	checkCommonTags(t, m)
+	if got := rows[0].Data.(*view.CountData).Value; got != 1 || m[tagKeyOutcome] != "committed" {
+		t.Errorf("got %d transactions %s, want 1 committed", got, m[tagKeyOutcome])
+	}
+	if rows, err := view.RetrieveData(TransactionDurationView.Name); err != nil || len(rows) != 0 {
+		t.Errorf("got durations %v, error %v, want no fast transaction to be recorded", rows, err)
+	}
+}
+
+func TestSetSlowTransactionThresholdInvalid(t *testing.T) {
+	if err := SetSlowTransactionThreshold(-time.Second); err == nil {
+		t.Error("got no error for a negative threshold")
+	}
+}
+
+func TestOCStats_OldestIdleSessionAge(t *testing.T) {
+	if err := EnableOldestIdleSessionAgeView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableOldestIdleSessionAgeView()
+
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 0}})
+	defer teardown()
+	sp := client.idleSessions
+	sh, err := sp.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sh.session
+	sh.recycle()
+	s.mu.Lock()
+	s.lastUseTime = time.Now().Add(-90 * time.Second)
+	s.mu.Unlock()
+
+	sp.mu.Lock()
+	sp.recordOldestIdleSessionAgeLocked(time.Now())
+	sp.mu.Unlock()
+
+	rows, err := view.RetrieveData(OldestIdleSessionAgeView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	checkCommonTags(t, getTagMap(rows[0].Tags))
+	if got := rows[0].Data.(*view.LastValueData).Value; got < 90 || got > 100 {
+		t.Errorf("got an oldest idle session age of %vs, want about 90s", got)
+	}
+}
+
+func TestOCStats_SessionPoolConfig(t *testing.T) {
+	if err := EnableSessionPoolConfigViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionPoolConfigViews()
+
+	_, _, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{
+		MinOpened:     10,
+		MaxOpened:     50,
+		WriteSessions: 0.3,
+	}})
+	defer teardown()
+
+	for _, test := range []struct {
+		view *view.View
+		want float64
+	}{
+		{ConfiguredMinSessionsView, 10},
+		{ConfiguredMaxSessionsView, 50},
+		{ConfiguredWriteFractionView, 0.3},
+	} {
+		rows, err := view.RetrieveData(test.view.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("%s: got %d rows, want 1", test.view.Name, len(rows))
+		}
+		checkCommonTags(t, getTagMap(rows[0].Tags))
+		if got := rows[0].Data.(*view.LastValueData).Value; got != test.want {
+			t.Errorf("%s: got %v, want %v", test.view.Name, got, test.want)
+		}
+	}
+}
+
+func TestOCStats_PoolExhaustedBlockDuration(t *testing.T) {
+	if err := EnablePoolExhaustedBlockDurationView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisablePoolExhaustedBlockDurationView()
+	blocked := func() float64 {
+		rows, err := view.RetrieveData(PoolExhaustedBlockDurationView.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum float64
+		for _, row := range rows {
+			checkCommonTags(t, getTagMap(row.Tags))
+			sum += row.Data.(*view.SumData).Value
+		}
+		return sum
+	}
+
+	// Waiting for a session the pool is allowed to create is not counted.
+	_, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 0, MaxOpened: 10}})
+	defer teardown()
+	if _, err := client.idleSessions.take(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := blocked(); got != 0 {
+		t.Fatalf("got %vms blocked on a pool below its maximum, want 0", got)
+	}
+
+	// Waiting for a session while the pool is at its maximum is counted.
+	_, client, teardown = setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 1, MaxOpened: 1}})
+	defer teardown()
+	sp := client.idleSessions
+	sh, err := sp.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		sh.recycle()
+	}()
+	if _, err := sp.take(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := blocked(); got < 50 {
+		t.Errorf("got %vms blocked on an exhausted pool, want at least 50ms", got)
+	}
+}
+
+func TestOCStats_SessionNotFoundCount(t *testing.T) {
+	if err := EnableSessionNotFoundCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionNotFoundCountView()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteStreamingSql,
+		stestutil.SimulatedExecutionTime{
+			Errors: []error{newSessionNotFoundError("projects/p/instances/i/databases/d/sessions/s")},
+		})
+	iter := client.Single().Query(context.Background(), NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	if err := iter.Do(func(r *Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := viewCount(t, SessionNotFoundCountView); got != 1 {
+		t.Errorf("got %d sessions not found, want 1", got)
+	}
+}
+
+func TestOCStats_SessionChurn(t *testing.T) {
+	if err := EnableSessionChurnViews(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionChurnViews()
+
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{SessionPoolConfig: SessionPoolConfig{MinOpened: 10}})
+	defer teardown()
+	waitFor(t, func() error {
+		if got := viewCount(t, SessionCreatedCountView); got != 10 {
+			return fmt.Errorf("got %d sessions created, want 10", got)
+		}
+		return nil
+	})
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteStreamingSql,
+		stestutil.SimulatedExecutionTime{
+			Errors: []error{newSessionNotFoundError("projects/p/instances/i/databases/d/sessions/s")},
+		})
+	iter := client.Single().Query(context.Background(), NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	if err := iter.Do(func(r *Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := viewCount(t, SessionDestroyedCountView); got != 1 {
+		t.Errorf("got %d sessions destroyed, want 1", got)
+	}
+}
+
+func TestOCStats_FinalizedSessionsCount(t *testing.T) {
+	if err := EnableFinalizedSessionsCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableFinalizedSessionsCountView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	sp := client.idleSessions
+	// A session that is returned to the pool is not counted.
+	sh, err := sp.take(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh.recycle()
+	// A session whose handle is dropped is counted by the finalizer.
+	if _, err := sp.take(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() error {
+		runtime.GC()
+		if got := viewCount(t, FinalizedSessionsCountView); got != 1 {
+			return fmt.Errorf("got %d finalized sessions, want 1", got)
+		}
+		return nil
+	})
+	// Counting an abandoned session does not return it to the pool.
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.numInUse != 1 {
+		t.Errorf("got %d sessions in use, want the abandoned session to stay checked out", sp.numInUse)
+	}
+}
+
+func TestOCStats_ResultSetChunks(t *testing.T) {
+	if err := EnableResultSetChunksView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableResultSetChunksView()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	ctx := context.Background()
+	stmt := NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums)
+	if err := client.Single().Query(ctx, stmt).Do(func(r *Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	// An iterator stopped early records the chunks received so far, once.
+	iter := client.Single().Query(ctx, stmt)
+	if _, err := iter.Next(); err != nil {
+		t.Fatal(err)
+	}
+	iter.Stop()
+	iter.Stop()
+
+	rows, err := view.RetrieveData(ResultSetChunksView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	m := getTagMap(rows[0].Tags)
+	checkCommonTags(t, m)
+	if m[tagKeyMethod] != "query" {
+		t.Errorf("got method %q, want query", m[tagKeyMethod])
+	}
+	if data := rows[0].Data.(*view.DistributionData); data.Count != 2 || data.Min < 1 {
+		t.Errorf("got %d streams with at least %v chunks, want 2 streams with at least 1 chunk", data.Count, data.Min)
+	}
+}
+
+func TestOCStats_UnavailableRetriesCount(t *testing.T) {
+	if err := EnableUnavailableRetriesCountView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableUnavailableRetriesCountView()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteSql,
+		stestutil.SimulatedExecutionTime{
+			Errors: []error{status.Error(codes.Unavailable, "unavailable"), status.Error(codes.Unavailable, "unavailable")},
+		})
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		_, err := tx.Update(ctx, NewStatement(stestutil.UpdateBarSetFoo))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := view.RetrieveData(UnavailableRetriesCountView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeyMethod]] += row.Data.(*view.CountData).Value
+	}
+	if want := map[string]int64{"ExecuteSql": 2}; !testEqual(got, want) {
+		t.Errorf("got retries %v, want %v", got, want)
+	}
+}
+
+func TestOCStats_MaxQueryLatency(t *testing.T) {
+	if err := EnableMaxQueryLatencyView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableMaxQueryLatencyView()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	maxLatency := func() int64 {
+		t.Helper()
+		rows, err := view.RetrieveData(MaxQueryLatencyView.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("got %d rows, want 1", len(rows))
+		}
+		checkCommonTags(t, getTagMap(rows[0].Tags))
+		return int64(rows[0].Data.(*view.LastValueData).Value)
+	}
+	query := func() {
+		t.Helper()
+		iter := client.Single().Query(context.Background(), NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+		if err := iter.Do(func(r *Row) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteStreamingSql,
+		stestutil.SimulatedExecutionTime{MinimumExecutionTime: 50 * time.Millisecond})
+	query()
+	if got := maxLatency(); got < 50 {
+		t.Fatalf("got a maximum query latency of %dms, want at least 50ms", got)
+	}
+	high := maxLatency()
+
+	// A faster query does not lower the maximum.
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteStreamingSql, stestutil.SimulatedExecutionTime{})
+	query()
+	if got := maxLatency(); got != high {
+		t.Errorf("got a maximum query latency of %dms after a faster query, want %dms", got, high)
+	}
+
+	client.ResetMaxQueryLatency()
+	if got := maxLatency(); got != 0 {
+		t.Errorf("got a maximum query latency of %dms after reset, want 0", got)
+	}
+	query()
+	if got := maxLatency(); got >= high {
+		t.Errorf("got a maximum query latency of %dms after reset and a fast query, want less than %dms", got, high)
+	}
+}
+
+func TestOCStats_QueriesInFlight(t *testing.T) {
+	if err := EnableQueriesInFlightView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableQueriesInFlightView()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	inFlight := func() float64 {
+		t.Helper()
+		rows, err := view.RetrieveData(QueriesInFlightView.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n float64
+		for _, row := range rows {
+			checkCommonTags(t, getTagMap(row.Tags))
+			n += row.Data.(*view.SumData).Value
+		}
+		return n
+	}
+	ctx := context.Background()
+	stmt := NewStatement(stestutil.SelectSingerIDAlbumIDAlbumTitleFromAlbums)
+
+	iter := client.Single().Query(ctx, stmt)
+	if _, err := iter.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := inFlight(); got != 1 {
+		t.Errorf("got %v queries in flight during a query, want 1", got)
+	}
+	iter.Stop()
+	if got := inFlight(); got != 0 {
+		t.Errorf("got %v queries in flight after Stop, want 0", got)
+	}
+
+	if err := client.Single().Query(ctx, stmt).Do(func(r *Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	server.TestSpanner.PutExecutionTime(stestutil.MethodExecuteStreamingSql,
+		stestutil.SimulatedExecutionTime{Errors: []error{status.Error(codes.InvalidArgument, "invalid query")}})
+	iter = client.Single().Query(ctx, stmt)
+	if _, err := iter.Next(); err == nil {
+		t.Fatal("got no error for a failing query")
+	}
+	if got := inFlight(); got != 0 {
+		t.Errorf("got %v queries in flight after the queries completed, want 0", got)
+	}
+	iter.Stop()
+	if got := inFlight(); got != 0 {
+		t.Errorf("got %v queries in flight after Stop, want 0", got)
+	}
+}
+
+func TestOCStats_SessionPoolEvents(t *testing.T) {
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	ctx := context.Background()
+	// Events are not recorded while the view is disabled.
+	if err := client.RecordSessionPoolEvent(ctx, "prewarm", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnableSessionPoolEventsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableSessionPoolEventsView()
+
+	for _, e := range []struct {
+		eventType string
+		n         int64
+	}{{"prewarm", 3}, {"evict", 1}, {"prewarm", 2}} {
+		if err := client.RecordSessionPoolEvent(ctx, e.eventType, e.n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, eventType := range []string{"", "bad\nevent", strings.Repeat("x", 256)} {
+		if err := client.RecordSessionPoolEvent(ctx, eventType, 1); err == nil {
+			t.Errorf("%q: got no error for an invalid event type", eventType)
+		}
+	}
+
+	rows, err := view.RetrieveData(SessionPoolEventsView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]float64)
+	for _, row := range rows {
+		m := getTagMap(row.Tags)
+		checkCommonTags(t, m)
+		got[m[tagKeyEventType]] += row.Data.(*view.SumData).Value
+	}
+	if want := map[string]float64{"prewarm": 5, "evict": 1}; !testEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestOCStats_AsyncRecording(t *testing.T) {
+	if err := EnableCommitAttemptsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer DisableCommitAttemptsView()
+	if err := EnableAsyncRecording(100); err != nil {
+		t.Fatal(err)
+	}
+	asyncEnabled := true
+	defer func() {
+		if asyncEnabled {
+			DisableAsyncRecording()
+		}
+	}()
+	if err := EnableAsyncRecording(100); err == nil {
+		t.Error("got no error enabling async recording twice")
+	}
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return tx.BufferWrite([]*Mutation{Insert("Users", []string{"name"}, []interface{}{"alice"})})
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Disabling async recording flushes the queued measurements.
+	DisableAsyncRecording()
+	asyncEnabled = false
+
+	rows, err := view.RetrieveData(CommitAttemptsView.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	checkCommonTags(t, getTagMap(rows[0].Tags))
+	if got := rows[0].Data.(*view.DistributionData).Count; got != 1 {
+		t.Errorf("got %d commits, want 1", got)
+	}
+}
+
+func TestAsyncRecorderDropsOldest(t *testing.T) {
+	r := newAsyncRecorder(2)
+	// The records are told apart by the number of their options.
+	var recs []asyncRecord
+	for i := 0; i < 3; i++ {
+		recs = append(recs, asyncRecord{ctx: context.Background(), opts: make([]stats.Options, i)})
+	}
+	for i, rec := range recs {
+		if got, want := r.enqueue(rec), i == 2; got != want {
+			t.Errorf("enqueue #%d dropped = %v, want %v", i, got, want)
+		}
+	}
+	for _, want := range recs[1:] {
+		if got := <-r.records; len(got.opts) != len(want.opts) {
+			t.Errorf("got record #%d, want #%d", len(got.opts), len(want.opts))
+		}
+	}
+}
+
+func TestEnableAsyncRecordingInvalid(t *testing.T) {
+	if err := EnableAsyncRecording(0); err == nil {
+		DisableAsyncRecording()
+		t.Error("got no error for a buffer size of 0")
+	}
+}
+
+// viewCount returns the total count recorded by the count view v, checking
+// the common tags of each row.
+func viewCount(t *testing.T, v *view.View) int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int64
+	for _, row := range rows {
+		checkCommonTags(t, getTagMap(row.Tags))
+		n += row.Data.(*view.CountData).Value
+	}
+	return n
+}
@@ -19,7 +19,6 @@ import (
 
 	"cloud.google.com/go/internal/trace"
 	"github.com/googleapis/gax-go/v2"
-	"go.opencensus.io/tag"
 	sppb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -93,7 +92,12 @@ func (c *Client) partitionedUpdate(ctx context.Context, statement Statement, opt
 			}
 		}
 	}
-	return executePdmlWithRetry(ctx)
+	count, err = executePdmlWithRetry(ctx)
+	if err == nil {
+		// Partitioned DML only returns a lower bound of the affected rows.
+		recordDMLRowsAffected(ctx, c.ct, "PartitionedUpdate", count, true)
+	}
+	return count, err
 }
 
 // executePdml executes the following steps:
@@ -119,8 +123,11 @@ func executePdml(ctx context.Context, sh *sessionHandle, req *sppb.ExecuteSqlReq
 		Selector: &sppb.TransactionSelector_Id{Id: res.Id},
 	}
 	resultSet, err := sh.getClient().ExecuteSql(contextWithOutgoingMetadata(ctx, sh.getMetadata()), req, gax.WithGRPCOptions(grpc.Header(&md)))
-	if getGFELatencyMetricsFlag() && md != nil && sh.session.pool != nil {
-		err := captureGFELatencyStats(tag.NewContext(ctx, sh.session.pool.tagMap), md, "executePdml_ExecuteSql")
+	if sh.session.pool != nil && sh.session.pool.sc.gfeLatencyMetricsEnabled() && md != nil {
+		ctxGFE, err := mergeTagMap(ctx, sh.session.pool.tagMap)
+		if err == nil {
+			err = captureGFELatencyStats(ctxGFE, md, "executePdml_ExecuteSql")
+		}
 		if err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 		}
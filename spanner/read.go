@@ -115,6 +115,82 @@ type RowIterator struct {
 	err          error
 	rows         []*Row
 	sawStats     bool
+
+	// ct and method tag the BytesReturned measurements of the iterator. No
+	// bytes are recorded if ct is nil.
+	ct     *commonTags
+	method string
+	// statsCtx is the context BytesReturned is recorded with. It is created
+	// when the first measurement is recorded.
+	statsCtx context.Context
+	// queryCtx is the context QueriesInFlight is recorded with while the
+	// query of the iterator is counted as in flight, or nil.
+	queryCtx context.Context
+	// chunks is the number of partial result sets received so far, or -1
+	// once it has been recorded as ResultSetChunks.
+	chunks int64
+	// queryStart is when the first result set of the query of the iterator
+	// was requested, while MaxQueryLatency waits for it, or zero.
+	queryStart time.Time
+}
+
+// recordResultSetChunks records the number of partial result sets the
+// iterator received, once, if the ResultSetChunks metric is enabled.
+func (r *RowIterator) recordResultSetChunks() {
+	if r.chunks < 0 || r.ct == nil || r.streamd == nil {
+		return
+	}
+	n := r.chunks
+	r.chunks = -1
+	if !isViewEnabled(ResultSetChunksView) {
+		return
+	}
+	ctx, err := contextWithCommonTags(r.streamd.ctx, r.ct, r.method)
+	if err != nil {
+		trace.TracePrintf(r.streamd.ctx, nil, "Error in adding tags for ResultSetChunks: %v", err)
+		return
+	}
+	recordStat(ctx, ResultSetChunks, n)
+}
+
+// startQuery counts the query of the iterator as in flight, if the iterator
+// is that of a query and the QueriesInFlight metric is enabled.
+func (r *RowIterator) startQuery() {
+	if r.queryCtx != nil || r.ct == nil || r.method != "query" || !isViewEnabled(QueriesInFlightView) {
+		return
+	}
+	ctx, err := contextWithCommonTags(r.streamd.ctx, r.ct, r.method)
+	if err != nil {
+		trace.TracePrintf(r.streamd.ctx, nil, "Error in adding tags for QueriesInFlight: %v", err)
+		return
+	}
+	r.queryCtx = ctx
+	recordStat(ctx, QueriesInFlight, 1)
+}
+
+// endQuery stops counting the query of the iterator as in flight, if it was
+// counted.
+func (r *RowIterator) endQuery() {
+	if r.queryCtx == nil {
+		return
+	}
+	recordStat(r.queryCtx, QueriesInFlight, -1)
+	r.queryCtx = nil
+}
+
+// recordBytesReturned records the size of prs as bytes returned by the
+// iterator.
+func (r *RowIterator) recordBytesReturned(prs *sppb.PartialResultSet) {
+	if r.statsCtx == nil {
+		ctx, err := contextWithCommonTags(r.streamd.ctx, r.ct, r.method)
+		if err != nil {
+			trace.TracePrintf(r.streamd.ctx, nil, "Error in adding tags for BytesReturned: %v", err)
+			r.ct = nil
+			return
+		}
+		r.statsCtx = ctx
+	}
+	recordStat(r.statsCtx, BytesReturned, int64(proto.Size(prs)))
 }
 
 // Next returns the next result. Its second return value is iterator.Done if
@@ -124,8 +200,22 @@ func (r *RowIterator) Next() (*Row, error) {
 	if r.err != nil {
 		return nil, r.err
 	}
+	r.startQuery()
+	if r.chunks == 0 && r.queryStart.IsZero() && r.ct != nil && r.method == "query" && isViewEnabled(MaxQueryLatencyView) {
+		r.queryStart = time.Now()
+	}
 	for len(r.rows) == 0 && r.streamd.next() {
 		prs := r.streamd.get()
+		if r.chunks >= 0 {
+			r.chunks++
+		}
+		if !r.queryStart.IsZero() {
+			recordQueryLatency(r.streamd.ctx, r.ct, time.Since(r.queryStart))
+			r.queryStart = time.Time{}
+		}
+		if r.ct != nil && isViewEnabled(BytesReturnedView) {
+			r.recordBytesReturned(prs)
+		}
 		if prs.Stats != nil {
 			r.sawStats = true
 			r.QueryPlan = prs.Stats.QueryPlan
@@ -144,6 +234,8 @@ func (r *RowIterator) Next() (*Row, error) {
 			r.Metadata = metadata
 		}
 		if r.err != nil {
+			r.endQuery()
+			r.recordResultSetChunks()
 			return nil, r.err
 		}
 		if !r.rowd.ts.IsZero() && r.setTimestamp != nil {
@@ -163,6 +255,8 @@ func (r *RowIterator) Next() (*Row, error) {
 	} else {
 		r.err = iterator.Done
 	}
+	r.endQuery()
+	r.recordResultSetChunks()
 	return nil, r.err
 }
 
@@ -215,6 +309,8 @@ func (r *RowIterator) Stop() {
 			defer trace.EndSpan(r.streamd.ctx, nil)
 		}
 	}
+	r.endQuery()
+	r.recordResultSetChunks()
 	if r.cancel != nil {
 		r.cancel()
 	}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"os"
+
+	"cloud.google.com/go/compute/metadata"
+	"go.opencensus.io/resource"
+	"go.opencensus.io/resource/resourcekeys"
+)
+
+// DetectResource returns the resource the process runs on, as found by the
+// given detectors, to be associated with the metrics of this package. If no
+// detector is given, the resource is read from the OC_RESOURCE_TYPE and
+// OC_RESOURCE_LABELS environment variables with resource.FromEnv, and
+// completed with DetectGCEResource. The labels found by earlier detectors
+// take precedence.
+//
+// The labels of the resource are not added to the tags of the views, so they
+// add no time series. Instead, set the resource on a meter, and enable the
+// views on it with EnableViewsOnMeter:
+//
+//	r, err := spanner.DetectResource(ctx)
+//	if err != nil {
+//		// TODO: Handle error.
+//	}
+//	meter := view.NewMeter()
+//	meter.SetResource(r)
+//	meter.Start()
+//	err = spanner.EnableViewsOnMeter(meter, spanner.OpenSessionCountView)
+//
+// The resource is reported with the data of the meter when it is read with
+// metricexport, as the OpenCensus Stackdriver exporter does, but not through
+// view.RegisterExporter.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func DetectResource(ctx context.Context, detectors ...resource.Detector) (*resource.Resource, error) {
+	if len(detectors) == 0 {
+		detectors = []resource.Detector{resource.FromEnv, DetectGCEResource}
+	}
+	return resource.MultiDetector(detectors...)(ctx)
+}
+
+// DetectGCEResource is a resource.Detector that returns the Compute Engine
+// instance the process runs on, with its project, zone, ID and name, or nil
+// if it does not run on Compute Engine. On Google Kubernetes Engine, which
+// is detected by the KUBERNETES_SERVICE_HOST environment variable, the
+// resource is a Kubernetes resource that additionally has the name of the
+// cluster, and the namespace and pod names taken from the NAMESPACE and
+// HOSTNAME environment variables, if set.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func DetectGCEResource(ctx context.Context) (*resource.Resource, error) {
+	if !metadata.OnGCE() {
+		return nil, nil
+	}
+	r := &resource.Resource{
+		Type:   resourcekeys.CloudType,
+		Labels: map[string]string{resourcekeys.CloudKeyProvider: resourcekeys.CloudProviderGCP},
+	}
+	for key, get := range map[string]func() (string, error){
+		resourcekeys.CloudKeyAccountID: metadata.ProjectID,
+		resourcekeys.CloudKeyZone:      metadata.Zone,
+		resourcekeys.HostKeyID:         metadata.InstanceID,
+		resourcekeys.HostKeyName:       metadata.InstanceName,
+	} {
+		v, err := get()
+		if err != nil {
+			return nil, err
+		}
+		r.Labels[key] = v
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		r.Type = resourcekeys.K8SType
+		// The cluster name is only an attribute of the nodes of GKE clusters.
+		if cluster, err := metadata.InstanceAttributeValue("cluster-name"); err == nil && cluster != "" {
+			r.Labels[resourcekeys.K8SKeyClusterName] = cluster
+		}
+		if ns := os.Getenv("NAMESPACE"); ns != "" {
+			r.Labels[resourcekeys.K8SKeyNamespaceName] = ns
+		}
+		if pod := os.Getenv("HOSTNAME"); pod != "" {
+			r.Labels[resourcekeys.K8SKeyPodName] = pod
+		}
+	}
+	return r, nil
+}
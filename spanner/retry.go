@@ -79,6 +79,28 @@ func (r *spannerRetryer) Retry(err error) (time.Duration, bool) {
 	return delay, true
 }
 
+// unavailableRetryCounter is a gax Retryer that counts the retries of
+// UNAVAILABLE errors made by the Retryer it wraps in the
+// UnavailableRetriesCount metric.
+type unavailableRetryCounter struct {
+	gax.Retryer
+	ct     *commonTags
+	method string
+}
+
+func (r *unavailableRetryCounter) Retry(err error) (time.Duration, bool) {
+	delay, shouldRetry := r.Retryer.Retry(err)
+	if shouldRetry && status.Code(err) == codes.Unavailable && isViewEnabled(UnavailableRetriesCountView) {
+		ctx, terr := contextWithCommonTags(context.Background(), r.ct, r.method)
+		if terr != nil {
+			trace.TracePrintf(ctx, nil, "Error in adding tags for UnavailableRetriesCount: %v", terr)
+		} else {
+			recordStat(ctx, UnavailableRetriesCount, 1)
+		}
+	}
+	return delay, shouldRetry
+}
+
 // runWithRetryOnAbortedOrSessionNotFound executes the given function and
 // retries it if it returns an Aborted or Session not found error. The retry
 // is delayed if the error was Aborted. The delay between retries is the delay
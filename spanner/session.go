@@ -24,6 +24,7 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"runtime"
 	"runtime/debug"
 	"sync"
 	"time"
@@ -72,6 +73,7 @@ func (sh *sessionHandle) recycle() {
 	}
 	p := sh.session.pool
 	tracked := sh.trackedSessionHandle
+	held := time.Since(sh.checkoutTime)
 	sh.session.recycle()
 	sh.session = nil
 	sh.trackedSessionHandle = nil
@@ -83,6 +85,9 @@ func (sh *sessionHandle) recycle() {
 		p.trackedSessionHandles.Remove(tracked)
 		p.mu.Unlock()
 	}
+	if isViewEnabled(SessionHoldDurationView) {
+		p.recordStat(context.Background(), SessionHoldDuration, int64(held/time.Millisecond))
+	}
 }
 
 // getID gets the Cloud Spanner session ID from the internal session object.
@@ -129,9 +134,26 @@ func (sh *sessionHandle) getTransactionID() transactionID {
 	return sh.session.tx
 }
 
-// destroy destroys the inner session object. It is safe to call destroy
-// multiple times and only the first call would attempt to
-// destroy the inner session object.
+// finalize is the finalizer of the session handles checked out while the
+// FinalizedSessionsCount metric is enabled. If the handle was neither
+// recycled nor destroyed before it became unreachable, its session is counted
+// as abandoned. The session is not returned to the pool, as it may still hold
+// a transaction.
+func (sh *sessionHandle) finalize() {
+	sh.mu.Lock()
+	s := sh.session
+	sh.mu.Unlock()
+	if s == nil || s.pool == nil {
+		return
+	}
+	if isViewEnabled(FinalizedSessionsCountView) {
+		s.pool.recordStat(context.Background(), FinalizedSessionsCount, 1)
+	}
+}
+
+// destroy destroys the inner session object after Cloud Spanner reported it
+// as not found. It is safe to call destroy multiple times and only the first
+// call would attempt to destroy the inner session object.
 func (sh *sessionHandle) destroy() {
 	sh.mu.Lock()
 	s := sh.session
@@ -153,7 +175,7 @@ func (sh *sessionHandle) destroy() {
 		p.trackedSessionHandles.Remove(tracked)
 		p.mu.Unlock()
 	}
-	s.destroy(false)
+	s.destroyNotFound()
 }
 
 // session wraps a Cloud Spanner session ID through which transactions are
@@ -200,6 +222,10 @@ type session struct {
 	tx transactionID
 	// firstHCDone indicates whether the first health check is done or not.
 	firstHCDone bool
+	// lastUseTime is the time the session was last returned to the idle
+	// list or successfully pinged, which resets its idle time in Cloud
+	// Spanner.
+	lastUseTime time.Time
 }
 
 // isValid returns true if the session is still valid for use.
@@ -233,11 +259,19 @@ func (s *session) ping() error {
 	_, span := octrace.StartSpan(ctx, "cloud.google.com/go/spanner.ping", octrace.WithSampler(octrace.NeverSample()))
 	defer span.End()
 
+	if s.pool != nil && isViewEnabled(SessionKeepAlivePingCountView) {
+		s.pool.recordStat(ctx, SessionKeepAlivePingCount, 1)
+	}
 	// s.getID is safe even when s is invalid.
 	_, err := s.client.ExecuteSql(contextWithOutgoingMetadata(ctx, s.md), &sppb.ExecuteSqlRequest{
 		Session: s.getID(),
 		Sql:     "SELECT 1",
 	})
+	if err == nil {
+		s.mu.Lock()
+		s.lastUseTime = time.Now()
+		s.mu.Unlock()
+	}
 	return err
 }
 
@@ -258,9 +292,20 @@ func (s *session) setIdleList(le *list.Element) *list.Element {
 	defer s.mu.Unlock()
 	old := s.idleList
 	s.idleList = le
+	if le != nil {
+		s.lastUseTime = time.Now()
+	}
 	return old
 }
 
+// getLastUseTime returns the time the session was last returned to the idle
+// list or successfully pinged.
+func (s *session) getLastUseTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastUseTime
+}
+
 // invalidate marks a session as invalid and returns the old validity.
 func (s *session) invalidate() bool {
 	s.mu.Lock()
@@ -333,6 +378,15 @@ func (s *session) destroy(isExpire bool) bool {
 	return s.destroyWithContext(ctx, isExpire)
 }
 
+// destroyNotFound destroys the session after Cloud Spanner reported it as
+// not found, and counts it in the SessionNotFoundCount metric if it was
+// still in the pool.
+func (s *session) destroyNotFound() {
+	if s.destroy(false) && isViewEnabled(SessionNotFoundCountView) {
+		s.pool.recordStat(context.Background(), SessionNotFoundCount, 1)
+	}
+}
+
 func (s *session) destroyWithContext(ctx context.Context, isExpire bool) bool {
 	// Remove s from session pool.
 	if !s.pool.remove(s, isExpire) {
@@ -649,23 +703,91 @@ func newSessionPool(sc *sessionClient, config SessionPoolConfig) (*sessionPool,
 		}
 	}
 	pool.recordStat(context.Background(), MaxAllowedSessionsCount, int64(config.MaxOpened))
+	pool.recordConfig()
 	close(pool.hc.ready)
 	return pool, nil
 }
 
+// recordStat records n for m, tagged with the common tags of the pool, the
+// given tags and any tags already in ctx.
 func (p *sessionPool) recordStat(ctx context.Context, m *stats.Int64Measure, n int64, tags ...tag.Tag) {
-	ctx = tag.NewContext(ctx, p.tagMap)
 	mutators := make([]tag.Mutator, len(tags))
 	for i, t := range tags {
 		mutators[i] = tag.Upsert(t.Key, t.Value)
 	}
-	ctx, err := tag.New(ctx, mutators...)
+	ctx, err := mergeTagMap(ctx, p.tagMap, mutators...)
 	if err != nil {
 		logf(p.sc.logger, "Failed to tag metrics, error: %v", err)
 	}
 	recordStat(ctx, m, n)
 }
 
+// recordConfig records the configuration of the pool, if the session pool
+// configuration metrics are enabled.
+func (p *sessionPool) recordConfig() {
+	if !isViewEnabled(ConfiguredMinSessionsView) && !isViewEnabled(ConfiguredMaxSessionsView) && !isViewEnabled(ConfiguredWriteFractionView) {
+		return
+	}
+	ctx, err := mergeTagMap(context.Background(), p.tagMap)
+	if err != nil {
+		logf(p.sc.logger, "Failed to tag metrics, error: %v", err)
+	}
+	recordWithMeters(ctx, stats.WithMeasurements(
+		ConfiguredMinSessions.M(int64(p.MinOpened)),
+		ConfiguredMaxSessions.M(int64(p.MaxOpened)),
+		ConfiguredWriteFraction.M(p.WriteSessions),
+	))
+}
+
+// recordAcquisitionQueueDepthLocked records the number of callers waiting for
+// a session, if the AcquisitionQueueDepth metric is enabled. p.mu must be
+// held, so that the depths are recorded in the order they change.
+func (p *sessionPool) recordAcquisitionQueueDepthLocked(ctx context.Context) {
+	if isViewEnabled(AcquisitionQueueDepthView) {
+		p.recordStat(ctx, AcquisitionQueueDepth, int64(p.numReadWaiters+p.numWriteWaiters))
+	}
+}
+
+// recordAcquisitionLatency records the time since start as the latency of a
+// session acquisition for a session of the given type.
+func (p *sessionPool) recordAcquisitionLatency(ctx context.Context, start time.Time, sessionType tag.Tag) {
+	if isViewEnabled(SessionAcquisitionLatencyView) || isViewEnabled(SessionAcquisitionLatencyByTypeView) {
+		p.recordStat(ctx, SessionAcquisitionLatency, int64(time.Since(start)/time.Millisecond), sessionType)
+	}
+}
+
+// isExhaustedLocked reports whether the pool has opened, or is creating, as
+// many sessions as it is allowed to, and has more waiters than sessions being
+// created, so that the latest waiter can only be served by a session returned
+// to the pool. p.mu must be held.
+func (p *sessionPool) isExhaustedLocked() bool {
+	return p.MaxOpened > 0 && p.numOpened >= p.MaxOpened && p.numReadWaiters+p.numWriteWaiters > p.createReqs
+}
+
+// recordPoolExhaustedBlock records the time since start spent waiting for a
+// session, if the wait started while the pool was exhausted and the
+// PoolExhaustedBlockDuration metric is enabled.
+func (p *sessionPool) recordPoolExhaustedBlock(ctx context.Context, exhausted bool, start time.Time) {
+	if !exhausted || !isViewEnabled(PoolExhaustedBlockDurationView) {
+		return
+	}
+	ctx, err := mergeTagMap(ctx, p.tagMap)
+	if err != nil {
+		logf(p.sc.logger, "Failed to tag metrics, error: %v", err)
+	}
+	recordWithMeters(ctx, stats.WithMeasurements(PoolExhaustedBlockDuration.M(float64(time.Since(start))/float64(time.Millisecond))))
+}
+
+// recordHitOrMiss records whether a session acquisition was served by an
+// idle session (a hit) or had to wait for one (a miss).
+func (p *sessionPool) recordHitOrMiss(ctx context.Context, hit bool) {
+	if hit && isViewEnabled(SessionPoolHitCountView) {
+		p.recordStat(ctx, SessionPoolHitCount, 1)
+	} else if !hit && isViewEnabled(SessionPoolMissCountView) {
+		p.recordStat(ctx, SessionPoolMissCount, 1)
+	}
+}
+
 func (p *sessionPool) initPool(numSessions uint64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -709,6 +831,9 @@ func (p *sessionPool) sessionReady(s *session) {
 		s.setIdleList(p.idleList.PushBack(s))
 	}
 	p.incNumReadsLocked(context.Background())
+	if isViewEnabled(SessionCreatedCountView) {
+		p.recordStat(context.Background(), SessionCreatedCount, 1)
+	}
 	// Notify other waiters blocking on session creation.
 	close(p.mayGetSession)
 	p.mayGetSession = make(chan struct{})
@@ -792,6 +917,10 @@ func (p *sessionPool) newSessionHandle(s *session) (sh *sessionHandle) {
 		sh.trackedSessionHandle = p.trackedSessionHandles.PushBack(sh)
 		p.mu.Unlock()
 		sh.stack = debug.Stack()
+	} else if isViewEnabled(FinalizedSessionsCountView) {
+		// Tracked session handles stay reachable from the pool, so they are
+		// never finalized.
+		runtime.SetFinalizer(sh, (*sessionHandle).finalize)
 	}
 	return sh
 }
@@ -882,6 +1011,9 @@ func (p *sessionPool) createSession(ctx context.Context) (*session, error) {
 	s.pool = p
 	p.hc.register(s)
 	doneCreate(true)
+	if isViewEnabled(SessionCreatedCountView) {
+		p.recordStat(ctx, SessionCreatedCount, 1)
+	}
 	return s, nil
 }
 
@@ -889,7 +1021,7 @@ func (p *sessionPool) isHealthy(s *session) bool {
 	if s.getNextCheck().Add(2 * p.hc.getInterval()).Before(time.Now()) {
 		if err := s.ping(); isSessionNotFoundError(err) {
 			// The session is already bad, continue to fetch/create a new one.
-			s.destroy(false)
+			s.destroyNotFound()
 			return false
 		}
 		p.hc.scheduledHC(s)
@@ -902,6 +1034,10 @@ func (p *sessionPool) isHealthy(s *session) bool {
 // for read operations.
 func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 	trace.TracePrintf(ctx, nil, "Acquiring a read-only session")
+	start := time.Now()
+	// waited is set when no idle session was available and take had to wait
+	// for one.
+	var waited bool
 	for {
 		var s *session
 
@@ -937,6 +1073,10 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 				continue
 			}
 			p.incNumInUse(ctx)
+			if !waited {
+				p.recordHitOrMiss(ctx, true)
+			}
+			p.recordAcquisitionLatency(ctx, start, tagReadOnlySession)
 			return p.newSessionHandle(s), nil
 		}
 
@@ -951,20 +1091,31 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 		}
 
 		p.numReadWaiters++
+		p.recordAcquisitionQueueDepthLocked(ctx)
 		mayGetSession := p.mayGetSession
+		exhausted := p.isExhaustedLocked()
 		p.mu.Unlock()
+		if !waited {
+			waited = true
+			p.recordHitOrMiss(ctx, false)
+		}
 		trace.TracePrintf(ctx, nil, "Waiting for read-only session to become available")
+		blockStart := time.Now()
 		select {
 		case <-ctx.Done():
 			trace.TracePrintf(ctx, nil, "Context done waiting for session")
-			p.recordStat(ctx, GetSessionTimeoutsCount, 1)
+			p.recordPoolExhaustedBlock(ctx, exhausted, blockStart)
+			p.recordStat(ctx, GetSessionTimeoutsCount, 1, tagReadOnlySession)
 			p.mu.Lock()
 			p.numReadWaiters--
+			p.recordAcquisitionQueueDepthLocked(ctx)
 			p.mu.Unlock()
 			return nil, p.errGetSessionTimeout(ctx)
 		case <-mayGetSession:
+			p.recordPoolExhaustedBlock(ctx, exhausted, blockStart)
 			p.mu.Lock()
 			p.numReadWaiters--
+			p.recordAcquisitionQueueDepthLocked(ctx)
 			if p.sessionCreationError != nil {
 				trace.TracePrintf(ctx, nil, "Error creating session: %v", p.sessionCreationError)
 				err := p.sessionCreationError
@@ -981,6 +1132,10 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 // returned should be used for read write transactions.
 func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, error) {
 	trace.TracePrintf(ctx, nil, "Acquiring a read-write session")
+	start := time.Now()
+	// waited is set when no idle session was available and takeWriteSession
+	// had to wait for one.
+	var waited bool
 	for {
 		var (
 			s   *session
@@ -1028,20 +1183,31 @@ func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, err
 			}
 
 			p.numWriteWaiters++
+			p.recordAcquisitionQueueDepthLocked(ctx)
 			mayGetSession := p.mayGetSession
+			exhausted := p.isExhaustedLocked()
 			p.mu.Unlock()
+			if !waited {
+				waited = true
+				p.recordHitOrMiss(ctx, false)
+			}
 			trace.TracePrintf(ctx, nil, "Waiting for read-write session to become available")
+			blockStart := time.Now()
 			select {
 			case <-ctx.Done():
 				trace.TracePrintf(ctx, nil, "Context done waiting for session")
-				p.recordStat(ctx, GetSessionTimeoutsCount, 1)
+				p.recordPoolExhaustedBlock(ctx, exhausted, blockStart)
+				p.recordStat(ctx, GetSessionTimeoutsCount, 1, tagReadWriteSession)
 				p.mu.Lock()
 				p.numWriteWaiters--
+				p.recordAcquisitionQueueDepthLocked(ctx)
 				p.mu.Unlock()
 				return nil, p.errGetSessionTimeout(ctx)
 			case <-mayGetSession:
+				p.recordPoolExhaustedBlock(ctx, exhausted, blockStart)
 				p.mu.Lock()
 				p.numWriteWaiters--
+				p.recordAcquisitionQueueDepthLocked(ctx)
 				if p.sessionCreationError != nil {
 					err := p.sessionCreationError
 					p.mu.Unlock()
@@ -1056,7 +1222,7 @@ func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, err
 			defer p.decNumBeingPrepared(ctx)
 			if err = s.prepareForWrite(ctx); err != nil {
 				if isSessionNotFoundError(err) {
-					s.destroy(false)
+					s.destroyNotFound()
 					trace.TracePrintf(ctx, map[string]interface{}{"sessionID": s.getID()},
 						"Session not found for write")
 					return nil, ToSpannerError(err)
@@ -1069,6 +1235,10 @@ func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, err
 			}
 		}
 		p.incNumInUse(ctx)
+		if !waited {
+			p.recordHitOrMiss(ctx, true)
+		}
+		p.recordAcquisitionLatency(ctx, start, tagReadWriteSession)
 		return p.newSessionHandle(s), nil
 	}
 }
@@ -1098,6 +1268,25 @@ func (p *sessionPool) recycle(s *session) bool {
 	return true
 }
 
+// recordOldestIdleSessionAgeLocked records the time since the least recently
+// used idle session was last used, or 0 if there are no idle sessions. The
+// caller must hold p.mu.
+func (p *sessionPool) recordOldestIdleSessionAgeLocked(now time.Time) {
+	var oldest time.Time
+	for _, l := range []*list.List{&p.idleList, &p.idleWriteList} {
+		for e := l.Front(); e != nil; e = e.Next() {
+			if t := e.Value.(*session).getLastUseTime(); oldest.IsZero() || t.Before(oldest) {
+				oldest = t
+			}
+		}
+	}
+	var age int64
+	if !oldest.IsZero() {
+		age = int64(now.Sub(oldest) / time.Second)
+	}
+	p.recordStat(context.Background(), OldestIdleSessionAge, age)
+}
+
 // remove atomically removes session s from the session pool and invalidates s.
 // If isExpire == true, the removal is triggered by session expiration and in
 // such cases, only idle sessions can be removed.
@@ -1126,6 +1315,9 @@ func (p *sessionPool) remove(s *session, isExpire bool) bool {
 		// Decrease the number of opened sessions.
 		p.numOpened--
 		p.recordStat(ctx, OpenSessionCount, int64(p.numOpened))
+		if isViewEnabled(SessionDestroyedCountView) {
+			p.recordStat(ctx, SessionDestroyedCount, 1)
+		}
 		// Broadcast that a session has been destroyed.
 		close(p.mayGetSession)
 		p.mayGetSession = make(chan struct{})
@@ -1464,7 +1656,7 @@ func (hc *healthChecker) healthCheck(s *session) {
 	}
 	if err := s.ping(); isSessionNotFoundError(err) {
 		// Ping failed, destroy the session.
-		s.destroy(false)
+		s.destroyNotFound()
 	}
 }
 
@@ -1595,6 +1787,9 @@ func (hc *healthChecker) maintainer() {
 			hc.pool.recordStat(context.Background(), MaxInUseSessionsCount, int64(hc.pool.maxNumInUse))
 			hc.pool.lastResetTime = now
 		}
+		if isViewEnabled(OldestIdleSessionAgeView) {
+			hc.pool.recordOldestIdleSessionAgeLocked(now)
+		}
 		hc.pool.mu.Unlock()
 		// Get the maximum number of sessions in use during the current
 		// maintenance window.
@@ -95,29 +95,60 @@ type sessionClient struct {
 	batchTimeout  time.Duration
 	logger        *log.Logger
 	callOptions   *vkit.CallOptions
+
+	// gfeLatencyMetrics is set when GFELatency and GFEHeaderMissingCount are
+	// recorded for the RPCs of this client. It is guarded by statsMu.
+	gfeLatencyMetrics bool
+
+	// maxQueryLatency is the highest query latency in milliseconds recorded
+	// as MaxQueryLatency since the last reset, or -1 if none was. It is
+	// guarded by maxQueryLatencyMu rather than mu, as it is updated by every
+	// query.
+	maxQueryLatencyMu sync.Mutex
+	maxQueryLatency   int64
 }
 
 // newSessionClient creates a session client to use for a database.
 func newSessionClient(connPool gtransport.ConnPool, database string, sessionLabels map[string]string, md metadata.MD, logger *log.Logger, callOptions *vkit.CallOptions) *sessionClient {
-	return &sessionClient{
-		connPool:      connPool,
-		database:      database,
-		id:            cidGen.nextID(database),
-		sessionLabels: sessionLabels,
-		md:            md,
-		batchTimeout:  time.Minute,
-		logger:        logger,
-		callOptions:   callOptions,
+	sc := &sessionClient{
+		connPool:        connPool,
+		database:        database,
+		id:              cidGen.nextID(database),
+		sessionLabels:   sessionLabels,
+		md:              md,
+		batchTimeout:    time.Minute,
+		logger:          logger,
+		callOptions:     callOptions,
+		maxQueryLatency: -1,
 	}
+	registerGFELatencyMetricsClient(sc)
+	return sc
 }
 
 func (sc *sessionClient) close() error {
+	unregisterGFELatencyMetricsClient(sc)
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 	sc.closed = true
 	return sc.connPool.Close()
 }
 
+// gfeLatencyMetricsEnabled reports whether GFE metrics are recorded for the
+// RPCs of this client.
+func (sc *sessionClient) gfeLatencyMetricsEnabled() bool {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	return sc.gfeLatencyMetrics
+}
+
+// setGFELatencyMetricsEnabled enables or disables GFE metrics for the RPCs of
+// this client.
+func (sc *sessionClient) setGFELatencyMetricsEnabled(enable bool) {
+	statsMu.Lock()
+	sc.gfeLatencyMetrics = enable
+	statsMu.Unlock()
+}
+
 // createSession creates one session for the database of the sessionClient. The
 // session is created using one synchronous RPC.
 func (sc *sessionClient) createSession(ctx context.Context) (*session, error) {
@@ -138,7 +169,7 @@ func (sc *sessionClient) createSession(ctx context.Context) (*session, error) {
 		Session:  &sppb.Session{Labels: sc.sessionLabels},
 	}, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil {
+	if sc.gfeLatencyMetricsEnabled() && md != nil {
 		_, instance, database, err := parseDatabaseName(sc.database)
 		if err != nil {
 			return nil, ToSpannerError(err)
@@ -254,13 +285,17 @@ func (sc *sessionClient) executeBatchCreateSessions(client *vkit.Client, createC
 			break
 		}
 		var mdForGFELatency metadata.MD
+		start := time.Now()
 		response, err := client.BatchCreateSessions(ctx, &sppb.BatchCreateSessionsRequest{
 			SessionCount:    remainingCreateCount,
 			Database:        sc.database,
 			SessionTemplate: &sppb.Session{Labels: labels},
 		}, gax.WithGRPCOptions(grpc.Header(&mdForGFELatency)))
+		if isViewEnabled(BatchCreateSessionsLatencyView) {
+			sc.recordBatchCreateSessionsLatency(ctx, time.Since(start))
+		}
 
-		if getGFELatencyMetricsFlag() && mdForGFELatency != nil {
+		if sc.gfeLatencyMetricsEnabled() && mdForGFELatency != nil {
 			_, instance, database, err := parseDatabaseName(sc.database)
 			if err != nil {
 				trace.TracePrintf(ctx, nil, "Error getting instance and database name: %v", err)
@@ -287,6 +322,9 @@ func (sc *sessionClient) executeBatchCreateSessions(client *vkit.Client, createC
 		}
 		actuallyCreated := int32(len(response.Session))
 		trace.TracePrintf(ctx, nil, "Received a batch of %d sessions", actuallyCreated)
+		if isViewEnabled(BatchCreateSessionsSizeView) {
+			sc.recordBatchCreateSessionsSize(ctx, remainingCreateCount, actuallyCreated)
+		}
 		for _, s := range response.Session {
 			consumer.sessionReady(&session{valid: true, client: client, id: s.Name, createTime: time.Now(), md: md, logger: sc.logger})
 		}
@@ -301,6 +339,43 @@ func (sc *sessionClient) executeBatchCreateSessions(client *vkit.Client, createC
 	}
 }
 
+// recordBatchCreateSessionsSize records the number of sessions requested and
+// returned by a BatchCreateSessions RPC.
+func (sc *sessionClient) recordBatchCreateSessionsSize(ctx context.Context, requested, returned int32) {
+	ct := getCommonTags(sc)
+	if ct == nil {
+		return
+	}
+	ctx, err := contextWithCommonTags(ctx, ct, "executeBatchCreateSessions")
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for BatchCreateSessionsSize: %v", err)
+		return
+	}
+	for typ, n := range map[string]int32{"requested": requested, "returned": returned} {
+		ctx, err := tag.New(ctx, tag.Upsert(tagKeyType, typ))
+		if err != nil {
+			trace.TracePrintf(ctx, nil, "Error in adding tags for BatchCreateSessionsSize: %v", err)
+			return
+		}
+		recordStat(ctx, BatchCreateSessionsSize, int64(n))
+	}
+}
+
+// recordBatchCreateSessionsLatency records the time a BatchCreateSessions RPC
+// took.
+func (sc *sessionClient) recordBatchCreateSessionsLatency(ctx context.Context, d time.Duration) {
+	ct := getCommonTags(sc)
+	if ct == nil {
+		return
+	}
+	ctx, err := contextWithCommonTags(ctx, ct, "executeBatchCreateSessions")
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for BatchCreateSessionsLatency: %v", err)
+		return
+	}
+	recordStat(ctx, BatchCreateSessionsLatency, int64(d/time.Millisecond))
+}
+
 func (sc *sessionClient) sessionWithID(id string) (*session, error) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
@@ -326,9 +401,45 @@ func (sc *sessionClient) nextClient() (*vkit.Client, error) {
 	if sc.callOptions != nil {
 		client.CallOptions = mergeCallOptions(client.CallOptions, sc.callOptions)
 	}
+	client.CallOptions = sc.countUnavailableRetries(client.CallOptions)
 	return client, nil
 }
 
+// countUnavailableRetries returns a copy of opts in which the retries of
+// UNAVAILABLE errors made by the retry settings of each method are counted in
+// the UnavailableRetriesCount metric, tagged with the name of the method.
+func (sc *sessionClient) countUnavailableRetries(opts *vkit.CallOptions) *vkit.CallOptions {
+	ct := getCommonTags(sc)
+	if ct == nil {
+		return opts
+	}
+	res := &vkit.CallOptions{}
+	resVal := reflect.ValueOf(res).Elem()
+	optsVal := reflect.ValueOf(opts).Elem()
+	t := optsVal.Type()
+
+	for i := 0; i < optsVal.NumField(); i++ {
+		method := t.Field(i).Name
+		methodOpts := optsVal.Field(i).Interface().([]gax.CallOption)
+
+		var settings gax.CallSettings
+		for _, opt := range methodOpts {
+			opt.Resolve(&settings)
+		}
+		if retry := settings.Retry; retry != nil {
+			methodOpts = append(methodOpts[:len(methodOpts):len(methodOpts)], gax.WithRetry(func() gax.Retryer {
+				r := retry()
+				if r == nil {
+					return nil
+				}
+				return &unavailableRetryCounter{Retryer: r, ct: ct, method: method}
+			}))
+		}
+		resVal.Field(i).Set(reflect.ValueOf(methodOpts))
+	}
+	return res
+}
+
 // mergeCallOptions merges two CallOptions into one and the first argument has
 // a lower order of precedence than the second one.
 func mergeCallOptions(a *vkit.CallOptions, b *vkit.CallOptions) *vkit.CallOptions {
@@ -0,0 +1,38 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spannertest
+
+import (
+	// Package spanner sets the hooks of testhook when it is initialized.
+	_ "cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/internal/testhook"
+)
+
+// ResetViewsForTesting clears the data aggregated so far by every view of
+// package spanner that is enabled, whether globally or on a meter through
+// spanner.EnableViewsOnMeter, by unregistering and registering it again. The
+// views stay enabled. It lets tests assert on the metrics recorded by each
+// test rather than on totals accumulated by all tests in the process.
+//
+// ResetViewsForTesting is for tests only. Exporters lose the data they have
+// not exported yet, and measurements recorded concurrently, or still queued
+// by asynchronous recording, may be recorded before or after the reset. Call
+// spanner.DisableAsyncRecording first to record the queued measurements
+// before the reset.
+func ResetViewsForTesting() error {
+	return testhook.ResetViews()
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spannertest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"go.opencensus.io/stats/view"
+)
+
+func TestResetViewsForTesting(t *testing.T) {
+	if err := spanner.EnableSessionPoolEventsView(); err != nil {
+		t.Fatal(err)
+	}
+	defer spanner.DisableSessionPoolEventsView()
+	meter := view.NewMeter()
+	meter.Start()
+	defer meter.Stop()
+	if err := spanner.EnableViewsOnMeter(meter, spanner.MaxAllowedSessionsCountView); err != nil {
+		t.Fatal(err)
+	}
+	defer spanner.DisableViewsOnMeter(meter, spanner.MaxAllowedSessionsCountView)
+
+	client, _, _, cleanup := makeClient(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := client.RecordSessionPoolEvent(ctx, "prewarm", 1); err != nil {
+		t.Fatal(err)
+	}
+	rowCount := func(retrieve func(string) ([]*view.Row, error), v *view.View) int {
+		rows, err := retrieve(v.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(rows)
+	}
+	for deadline := time.Now().Add(5 * time.Second); rowCount(meter.RetrieveData, spanner.MaxAllowedSessionsCountView) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("no data recorded to the meter")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rowCount(view.RetrieveData, spanner.SessionPoolEventsView) == 0 {
+		t.Fatal("no data recorded")
+	}
+
+	if err := ResetViewsForTesting(); err != nil {
+		t.Fatal(err)
+	}
+	if got := rowCount(view.RetrieveData, spanner.SessionPoolEventsView); got != 0 {
+		t.Errorf("got %d rows after reset, want 0", got)
+	}
+	if got := rowCount(meter.RetrieveData, spanner.MaxAllowedSessionsCountView); got != 0 {
+		t.Errorf("got %d rows on the meter after reset, want 0", got)
+	}
+
+	// The views stay enabled.
+	if err := client.RecordSessionPoolEvent(ctx, "prewarm", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := rowCount(view.RetrieveData, spanner.SessionPoolEventsView); got != 1 {
+		t.Errorf("got %d rows after recording again, want 1", got)
+	}
+}
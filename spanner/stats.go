@@ -16,18 +16,30 @@ package spanner
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"cloud.google.com/go/internal/trace"
 	"cloud.google.com/go/internal/version"
+	"cloud.google.com/go/spanner/internal/testhook"
+	"go.opencensus.io/metric/metricdata"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	octrace "go.opencensus.io/trace"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 )
 
+func init() {
+	testhook.ResetViews = resetViews
+}
+
 const statsPrefix = "cloud.google.com/go/spanner/"
 
 var (
@@ -36,6 +48,9 @@ var (
 	tagKeyInstance   = tag.MustNewKey("instance_id")
 	tagKeyLibVersion = tag.MustNewKey("library_version")
 	tagKeyType       = tag.MustNewKey("type")
+	tagKeyOutcome    = tag.MustNewKey("outcome")
+	tagKeyRowCount   = tag.MustNewKey("row_count")
+	tagKeyEventType  = tag.MustNewKey("event_type")
 	tagCommonKeys    = []tag.Key{tagKeyClientID, tagKeyDatabase, tagKeyInstance, tagKeyLibVersion}
 
 	tagNumInUseSessions = tag.Tag{Key: tagKeyType, Value: "num_in_use_sessions"}
@@ -43,14 +58,168 @@ var (
 	tagNumReadSessions  = tag.Tag{Key: tagKeyType, Value: "num_read_sessions"}
 	tagNumWriteSessions = tag.Tag{Key: tagKeyType, Value: "num_write_prepared_sessions"}
 	tagKeyMethod        = tag.MustNewKey("grpc_client_method")
+	tagKeySessionType   = tag.MustNewKey("session_type")
+	tagReadOnlySession  = tag.Tag{Key: tagKeySessionType, Value: "read_only"}
+	tagReadWriteSession = tag.Tag{Key: tagKeySessionType, Value: "read_write"}
 	// gfeLatencyMetricsEnabled is used to track if GFELatency and GFEHeaderMissingCount need to be recorded
+	// by clients that are created from now on
 	gfeLatencyMetricsEnabled = false
-	// mutex to avoid data race in reading/writing the above flag
+	// gfeLatencyMetricsClients tracks the open session clients, so that the
+	// package-level GFE functions can enable or disable GFE metrics for all
+	// of them
+	gfeLatencyMetricsClients = map[*sessionClient]bool{}
+	// enabledViews tracks the opt-in views that have been enabled, so that
+	// their measures are only recorded while somebody is viewing them
+	enabledViews = map[*view.View]bool{}
+	// meterViews tracks the views enabled on caller-supplied meters, which
+	// receive all measurements in addition to the default meter
+	meterViews = map[view.Meter]map[*view.View]bool{}
+	// asyncStats is the recorder of measurements while asynchronous
+	// recording is enabled through EnableAsyncRecording, or nil
+	asyncStats *asyncRecorder
+	// slowTransactionThreshold is the shortest duration of a transaction
+	// that is recorded by TransactionDuration, set through
+	// SetSlowTransactionThreshold
+	slowTransactionThreshold time.Duration
+	// mutex to avoid data race in reading/writing the above flags and maps
 	statsMu = sync.RWMutex{}
 )
 
 func recordStat(ctx context.Context, m *stats.Int64Measure, n int64) {
-	stats.Record(ctx, m.M(n))
+	recordWithMeters(ctx, stats.WithMeasurements(m.M(n)))
+}
+
+// recordWithMeters records the measurements in opts to the default meter and
+// to each meter that has views enabled through EnableViewsOnMeter. While
+// asynchronous recording is enabled, the measurements are only queued for
+// recording, and any error recording them is not reported.
+func recordWithMeters(ctx context.Context, opts ...stats.Options) error {
+	statsMu.RLock()
+	if r := asyncStats; r != nil {
+		dropped := r.enqueue(asyncRecord{ctx: ctx, opts: opts})
+		statsMu.RUnlock()
+		if dropped && isViewEnabled(AsyncRecordDroppedCountView) {
+			recordWithMetersSync(ctx, stats.WithMeasurements(AsyncRecordDroppedCount.M(1)))
+		}
+		return nil
+	}
+	statsMu.RUnlock()
+	return recordWithMetersSync(ctx, opts...)
+}
+
+// recordWithMetersSync records the measurements in opts like
+// recordWithMeters, but always synchronously.
+func recordWithMetersSync(ctx context.Context, opts ...stats.Options) error {
+	err := stats.RecordWithOptions(ctx, opts...)
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	for meter := range meterViews {
+		if merr := stats.RecordWithOptions(ctx, append(opts, stats.WithRecorder(meter))...); err == nil {
+			err = merr
+		}
+	}
+	return err
+}
+
+// asyncRecord is a measurement queued for asynchronous recording, with the
+// context holding its tags.
+type asyncRecord struct {
+	ctx  context.Context
+	opts []stats.Options
+}
+
+// asyncRecorder records measurements on a background goroutine, so that
+// recording them does not contend with the RPCs that produce them.
+type asyncRecorder struct {
+	records chan asyncRecord
+	done    chan struct{}
+}
+
+func newAsyncRecorder(bufferSize int) *asyncRecorder {
+	return &asyncRecorder{
+		records: make(chan asyncRecord, bufferSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// run records the queued measurements until the queue is closed.
+func (r *asyncRecorder) run() {
+	defer close(r.done)
+	for rec := range r.records {
+		recordWithMetersSync(rec.ctx, rec.opts...)
+	}
+}
+
+// enqueue queues rec for recording without blocking. If the queue is full,
+// the oldest queued measurement is dropped to make room for rec, and enqueue
+// reports true.
+func (r *asyncRecorder) enqueue(rec asyncRecord) (dropped bool) {
+	for {
+		select {
+		case r.records <- rec:
+			return dropped
+		default:
+		}
+		select {
+		case <-r.records:
+			dropped = true
+		default:
+		}
+	}
+}
+
+// EnableAsyncRecording makes the measurements of this package be recorded
+// by a background goroutine instead of by the goroutine that produces them,
+// which removes the contention of recording from the hot paths of RPCs.
+// Measurements are queued in a buffer of bufferSize measurements. If the
+// buffer is full, because the views or exporters cannot keep up, the oldest
+// queued measurement is dropped and counted by the AsyncRecordDroppedCount
+// metric, if enabled, instead of blocking the RPC.
+//
+// As a consequence, a measurement may not be included in the data of a view
+// yet right after the operation that produced it, and some measurements
+// may be lost. Errors recording measurements are not reported.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func EnableAsyncRecording(bufferSize int) error {
+	if bufferSize <= 0 {
+		return fmt.Errorf("spanner: invalid async recording buffer size %d, must be positive", bufferSize)
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if asyncStats != nil {
+		return fmt.Errorf("spanner: async recording is already enabled")
+	}
+	asyncStats = newAsyncRecorder(bufferSize)
+	go asyncStats.run()
+	return nil
+}
+
+// DisableAsyncRecording makes the measurements of this package be recorded
+// synchronously again. It returns once the measurements queued so far have
+// been recorded.
+func DisableAsyncRecording() {
+	statsMu.Lock()
+	r := asyncStats
+	asyncStats = nil
+	statsMu.Unlock()
+	if r == nil {
+		return
+	}
+	close(r.records)
+	<-r.done
+}
+
+// recordStatWithSpanContext records n for m like recordStat, and attaches
+// the span context of the span in ctx, if any, to the measurement. Exporters
+// that support exemplars can use it to link the measurement to its trace.
+func recordStatWithSpanContext(ctx context.Context, m *stats.Int64Measure, n int64) error {
+	opts := []stats.Options{stats.WithMeasurements(m.M(n))}
+	if span := octrace.FromContext(ctx); span != nil {
+		opts = append(opts, stats.WithAttachments(metricdata.Attachments{
+			metricdata.AttachmentKeySpanContext: span.SpanContext(),
+		}))
+	}
+	return recordWithMeters(ctx, opts...)
 }
 
 var (
@@ -133,6 +302,17 @@ var (
 		TagKeys:     tagCommonKeys,
 	}
 
+	// GetSessionTimeoutsCountByTypeView is a view of the count of
+	// GetSessionTimeoutsCount by the type of session, read_only or
+	// read_write, that was requested.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	GetSessionTimeoutsCountByTypeView = &view.View{
+		Name:        statsPrefix + "get_session_timeouts_by_type",
+		Measure:     GetSessionTimeoutsCount,
+		Aggregation: view.Count(),
+		TagKeys:     append(tagCommonKeys, tagKeySessionType),
+	}
+
 	// AcquiredSessionsCount is the number of sessions acquired from
 	// the session pool.
 	AcquiredSessionsCount = stats.Int64(
@@ -199,105 +379,1370 @@ var (
 		Aggregation: view.Count(),
 		TagKeys:     append(tagCommonKeys, tagKeyMethod),
 	}
-)
 
-// EnableStatViews enables all views of metrics relate to session management.
-func EnableStatViews() error {
-	return view.Register(
-		OpenSessionCountView,
-		MaxAllowedSessionsCountView,
-		SessionsCountView,
-		MaxInUseSessionsCountView,
-		GetSessionTimeoutsCountView,
-		AcquiredSessionsCountView,
-		ReleasedSessionsCountView,
+	// SessionPoolHitCount is the number of session acquisitions that were
+	// served by an idle session in the pool without waiting.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolHitCount = stats.Int64(
+		statsPrefix+"session_pool_hit_count",
+		"The number of session acquisitions served by an idle session in the pool.",
+		stats.UnitDimensionless,
 	)
-}
 
-// EnableGfeLatencyView enables GFELatency metric
-func EnableGfeLatencyView() error {
-	setGFELatencyMetricsFlag(true)
-	return view.Register(GFELatencyView)
-}
+	// SessionPoolHitCountView is a view of the count of SessionPoolHitCount.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolHitCountView = &view.View{
+		Measure:     SessionPoolHitCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
 
-// EnableGfeHeaderMissingCountView enables GFEHeaderMissingCount metric
-func EnableGfeHeaderMissingCountView() error {
-	setGFELatencyMetricsFlag(true)
-	return view.Register(GFEHeaderMissingCountView)
-}
+	// SessionPoolMissCount is the number of session acquisitions that found
+	// no idle session in the pool and had to wait for one to be created or
+	// returned.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolMissCount = stats.Int64(
+		statsPrefix+"session_pool_miss_count",
+		"The number of session acquisitions that had to wait for a session.",
+		stats.UnitDimensionless,
+	)
 
-// EnableGfeLatencyAndHeaderMissingCountViews enables GFEHeaderMissingCount and GFELatency metric
-func EnableGfeLatencyAndHeaderMissingCountViews() error {
-	setGFELatencyMetricsFlag(true)
-	return view.Register(
-		GFELatencyView,
-		GFEHeaderMissingCountView,
+	// SessionPoolMissCountView is a view of the count of SessionPoolMissCount.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolMissCountView = &view.View{
+		Measure:     SessionPoolMissCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// SessionAcquisitionLatency is the time in milliseconds it took to
+	// acquire a session from the pool, including any time spent waiting for a
+	// session to be created or returned.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionAcquisitionLatency = stats.Int64(
+		statsPrefix+"session_acquisition_latency",
+		"The time it took to acquire a session from the pool.",
+		stats.UnitMilliseconds,
 	)
-}
 
-func getGFELatencyMetricsFlag() bool {
-	statsMu.RLock()
-	defer statsMu.RUnlock()
-	return gfeLatencyMetricsEnabled
-}
+	sessionAcquisitionLatencyDistribution = view.Distribution(0.0, 0.5, 1.0, 2.0, 5.0, 10.0, 20.0, 50.0, 100.0, 200.0,
+		500.0, 1000.0, 2000.0, 5000.0, 10000.0, 30000.0, 60000.0)
 
-func setGFELatencyMetricsFlag(enable bool) {
-	statsMu.Lock()
-	gfeLatencyMetricsEnabled = enable
-	statsMu.Unlock()
-}
+	// SessionAcquisitionLatencyView is a view of the distribution of
+	// SessionAcquisitionLatency values.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionAcquisitionLatencyView = &view.View{
+		Measure:     SessionAcquisitionLatency,
+		Aggregation: sessionAcquisitionLatencyDistribution,
+		TagKeys:     tagCommonKeys,
+	}
 
-// DisableGfeLatencyAndHeaderMissingCountViews disables GFEHeaderMissingCount and GFELatency metric
-func DisableGfeLatencyAndHeaderMissingCountViews() {
-	setGFELatencyMetricsFlag(false)
-	view.Unregister(
-		GFELatencyView,
-		GFEHeaderMissingCountView,
+	// SessionAcquisitionLatencyByTypeView is a view of the distribution of
+	// SessionAcquisitionLatency values by the type of session, read_only or
+	// read_write, that was requested.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionAcquisitionLatencyByTypeView = &view.View{
+		Name:        statsPrefix + "session_acquisition_latency_by_type",
+		Measure:     SessionAcquisitionLatency,
+		Aggregation: sessionAcquisitionLatencyDistribution,
+		TagKeys:     append(tagCommonKeys, tagKeySessionType),
+	}
+
+	// BytesReturned is the number of bytes of the result sets returned by
+	// streaming reads and queries, recorded as the results are consumed.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BytesReturned = stats.Int64(
+		statsPrefix+"bytes_returned",
+		"The number of bytes returned by streaming reads and queries.",
+		stats.UnitBytes,
 	)
-}
 
-func captureGFELatencyStats(ctx context.Context, md metadata.MD, keyMethod string) error {
-	if len(md.Get("server-timing")) == 0 {
-		recordStat(ctx, GFEHeaderMissingCount, 1)
-		return nil
+	// BytesReturnedView is a view of the sum of BytesReturned, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BytesReturnedView = &view.View{
+		Measure:     BytesReturned,
+		Aggregation: view.Sum(),
+		TagKeys:     append(tagCommonKeys, tagKeyMethod),
 	}
-	serverTiming := md.Get("server-timing")[0]
-	gfeLatency, err := strconv.Atoi(strings.TrimPrefix(serverTiming, "gfet4t7; dur="))
-	if !strings.HasPrefix(serverTiming, "gfet4t7; dur=") || err != nil {
-		return err
+
+	// PartitionCount is the number of partitions returned by PartitionRead
+	// and PartitionQuery requests.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	PartitionCount = stats.Int64(
+		statsPrefix+"partition_count",
+		"The number of partitions returned by a partitioned read or query.",
+		stats.UnitDimensionless,
+	)
+
+	// PartitionCountView is a view of the distribution of PartitionCount
+	// values, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	PartitionCountView = &view.View{
+		Measure:     PartitionCount,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 4.0, 8.0, 16.0, 32.0, 64.0, 128.0, 256.0, 512.0, 1024.0, 2048.0, 4096.0, 8192.0),
+		TagKeys:     append(tagCommonKeys, tagKeyMethod),
 	}
-	// Record GFE latency with OpenCensus.
-	ctx = tag.NewContext(ctx, tag.FromContext(ctx))
-	ctx, err = tag.New(ctx, tag.Insert(tagKeyMethod, keyMethod))
-	if err != nil {
-		return err
+
+	// DMLRowsAffected is the number of rows affected by DML statements, as
+	// reported by Cloud Spanner when they were executed by Update,
+	// BatchUpdate or PartitionedUpdate, whether or not their read/write
+	// transaction commits.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	DMLRowsAffected = stats.Int64(
+		statsPrefix+"dml_rows_affected",
+		"The number of rows affected by DML statements.",
+		stats.UnitDimensionless,
+	)
+
+	// DMLRowsAffectedView is a view of the sum of DMLRowsAffected values,
+	// by method and by row count: "exact" for the exact counts of Update and
+	// BatchUpdate, or "lower_bound" for the counts of PartitionedUpdate,
+	// which are a lower bound of the rows it affected.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	DMLRowsAffectedView = &view.View{
+		Measure:     DMLRowsAffected,
+		Aggregation: view.Sum(),
+		TagKeys:     append(tagCommonKeys, tagKeyMethod, tagKeyRowCount),
 	}
-	recordStat(ctx, GFELatency, int64(gfeLatency))
-	return nil
-}
 
-func checkCommonTagsGFELatency(t *testing.T, m map[tag.Key]string) {
-	// We only check prefix because client ID increases if we create
-	// multiple clients for the same database.
-	if !strings.HasPrefix(m[tagKeyClientID], "client") {
-		t.Fatalf("Incorrect client ID: %v", m[tagKeyClientID])
+	// CommitAttempts is the number of attempts a read/write transaction
+	// took to commit, recorded when it commits: 1 if it committed at the
+	// first attempt, and one more for each time it was retried because it
+	// was aborted or its session was not found.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CommitAttempts = stats.Int64(
+		statsPrefix+"commit_attempts",
+		"The number of attempts a read/write transaction took to commit.",
+		stats.UnitDimensionless,
+	)
+
+	// CommitAttemptsView is a view of the distribution of CommitAttempts
+	// values.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	CommitAttemptsView = &view.View{
+		Measure:     CommitAttempts,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 3.0, 4.0, 5.0, 10.0, 20.0, 50.0),
+		TagKeys:     tagCommonKeys,
 	}
-	if m[tagKeyLibVersion] != version.Repo {
-		t.Fatalf("Incorrect library version: %v", m[tagKeyLibVersion])
+
+	// TransactionDuration is the wall-clock time in milliseconds a
+	// read/write transaction run by Client.ReadWriteTransaction took, from
+	// the start of its first attempt until it committed or failed, including
+	// all its retries.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TransactionDuration = stats.Int64(
+		statsPrefix+"transaction_duration",
+		"The time a read/write transaction took, including retries.",
+		stats.UnitMilliseconds,
+	)
+
+	// TransactionDurationView is a view of the distribution of
+	// TransactionDuration values, by outcome: "committed", "aborted" if the
+	// transaction failed because it was aborted, or "failed". If a threshold
+	// is set with SetSlowTransactionThreshold, it only holds the transactions
+	// that took at least as long as the threshold; TransactionCountView
+	// counts all of them.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TransactionDurationView = &view.View{
+		Measure: TransactionDuration,
+		Aggregation: view.Distribution(0.0, 5.0, 10.0, 25.0, 50.0, 100.0, 250.0, 500.0, 1000.0, 2500.0, 5000.0, 10000.0,
+			30000.0, 60000.0, 300000.0, 3600000.0),
+		TagKeys: append(tagCommonKeys, tagKeyOutcome),
 	}
-}
 
-func createContextAndCaptureGFELatencyMetrics(ctx context.Context, ct *commonTags, md metadata.MD, keyMethod string) error {
-	var ctxGFE, err = tag.New(ctx,
-		tag.Upsert(tagKeyClientID, ct.clientID),
-		tag.Upsert(tagKeyDatabase, ct.database),
-		tag.Upsert(tagKeyInstance, ct.instance),
-		tag.Upsert(tagKeyLibVersion, ct.libVersion),
+	// TransactionCount is the number of read/write transactions run by
+	// Client.ReadWriteTransaction that committed or failed. Unlike
+	// TransactionDuration, it is recorded for every transaction, whatever
+	// the threshold set with SetSlowTransactionThreshold.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TransactionCount = stats.Int64(
+		statsPrefix+"transaction_count",
+		"The number of read/write transactions that committed or failed.",
+		stats.UnitDimensionless,
 	)
-	if err != nil {
-		return err
+
+	// TransactionCountView is a view of the count of TransactionCount
+	// values, by the same outcomes as TransactionDurationView.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	TransactionCountView = &view.View{
+		Measure:     TransactionCount,
+		Aggregation: view.Count(),
+		TagKeys:     append(tagCommonKeys, tagKeyOutcome),
 	}
-	return captureGFELatencyStats(ctxGFE, md, keyMethod)
+
+	// OldestIdleSessionAge is the time in seconds since the least recently
+	// used idle session in the pool was last used or pinged, recorded by the
+	// pool maintainer on each of its cycles, or 0 if there are no idle
+	// sessions. Cloud Spanner deletes sessions that are idle for about an
+	// hour, so a value that approaches this shows that sessions are not kept
+	// alive often enough.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OldestIdleSessionAge = stats.Int64(
+		statsPrefix+"oldest_idle_session_age",
+		"The time since the least recently used idle session was last used.",
+		"s",
+	)
+
+	// OldestIdleSessionAgeView is a view of the last recorded value of
+	// OldestIdleSessionAge.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	OldestIdleSessionAgeView = &view.View{
+		Measure:     OldestIdleSessionAge,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// AcquisitionQueueDepth is the number of callers waiting for a session
+	// to become available, recorded whenever a caller starts or stops
+	// waiting. A rising depth shows that the pool is exhausted before
+	// requests start to time out.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AcquisitionQueueDepth = stats.Int64(
+		statsPrefix+"acquisition_queue_depth",
+		"The number of callers waiting for a session from the session pool.",
+		stats.UnitDimensionless,
+	)
+
+	// AcquisitionQueueDepthView is a view of the last value of
+	// AcquisitionQueueDepth.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AcquisitionQueueDepthView = &view.View{
+		Measure:     AcquisitionQueueDepth,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// SessionKeepAlivePingCount is the number of pings sent to keep idle
+	// sessions alive, and to check the health of sessions that have not been
+	// used for a while.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionKeepAlivePingCount = stats.Int64(
+		statsPrefix+"session_keepalive_ping_count",
+		"The number of keepalive pings sent for sessions in the session pool.",
+		stats.UnitDimensionless,
+	)
+
+	// SessionKeepAlivePingCountView is a view of the count of
+	// SessionKeepAlivePingCount.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionKeepAlivePingCountView = &view.View{
+		Measure:     SessionKeepAlivePingCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// BatchCreateSessionsSize is the number of sessions requested and
+	// returned by each BatchCreateSessions RPC, distinguished by the type tag,
+	// which is either "requested" or "returned".
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BatchCreateSessionsSize = stats.Int64(
+		statsPrefix+"batch_create_sessions_size",
+		"The number of sessions requested and returned by a BatchCreateSessions RPC.",
+		stats.UnitDimensionless,
+	)
+
+	// BatchCreateSessionsSizeView is a view of the distribution of
+	// BatchCreateSessionsSize values, by type.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BatchCreateSessionsSizeView = &view.View{
+		Measure:     BatchCreateSessionsSize,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 5.0, 10.0, 25.0, 50.0, 100.0, 250.0, 500.0, 1000.0),
+		TagKeys:     append(tagCommonKeys, tagKeyType),
+	}
+
+	// BatchCreateSessionsLatency is the time in milliseconds each
+	// BatchCreateSessions RPC took, whether it succeeded or failed. As the
+	// session pool creates its sessions with these RPCs, it shows how long
+	// the pool takes to warm up, for example after scaling out.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BatchCreateSessionsLatency = stats.Int64(
+		statsPrefix+"batch_create_sessions_latency",
+		"The latency of BatchCreateSessions RPCs.",
+		stats.UnitMilliseconds,
+	)
+
+	// BatchCreateSessionsLatencyView is a view of the distribution of
+	// BatchCreateSessionsLatency values.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	BatchCreateSessionsLatencyView = &view.View{
+		Measure: BatchCreateSessionsLatency,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 5.0, 10.0, 20.0, 50.0, 100.0, 200.0, 500.0, 1000.0, 2000.0,
+			5000.0, 10000.0, 30000.0, 60000.0),
+		TagKeys: tagCommonKeys,
+	}
+
+	// SessionHoldDuration is the time in milliseconds between a session being
+	// checked out of the pool and being returned to it.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionHoldDuration = stats.Int64(
+		statsPrefix+"session_hold_duration",
+		"The time a session was checked out of the pool before it was returned.",
+		stats.UnitMilliseconds,
+	)
+
+	// SessionHoldDurationView is a view of the distribution of
+	// SessionHoldDuration values.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionHoldDurationView = &view.View{
+		Measure: SessionHoldDuration,
+		Aggregation: view.Distribution(0.0, 1.0, 5.0, 10.0, 50.0, 100.0, 500.0, 1000.0, 5000.0, 10000.0, 30000.0,
+			60000.0, 300000.0, 600000.0, 1800000.0, 3600000.0),
+		TagKeys: tagCommonKeys,
+	}
+)
+
+var (
+	// SessionNotFoundCount is the number of sessions that were removed from
+	// the pool because Cloud Spanner reported them as not found, for example
+	// because they were deleted by the backend after a failover.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionNotFoundCount = stats.Int64(
+		statsPrefix+"session_not_found_count",
+		"The number of sessions removed from the pool because they were not found.",
+		stats.UnitDimensionless,
+	)
+
+	// SessionNotFoundCountView is a view of the total number of sessions
+	// removed from the pool because they were not found.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionNotFoundCountView = &view.View{
+		Measure:     SessionNotFoundCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// SessionCreatedCount is the number of sessions created by the session
+	// pool.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionCreatedCount = stats.Int64(
+		statsPrefix+"session_created_count",
+		"The number of sessions created by the pool.",
+		stats.UnitDimensionless,
+	)
+
+	// SessionDestroyedCount is the number of sessions removed from the
+	// session pool and deleted, for whatever reason: because they expired,
+	// were not found, or the pool shrank.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionDestroyedCount = stats.Int64(
+		statsPrefix+"session_destroyed_count",
+		"The number of sessions removed from the pool and deleted.",
+		stats.UnitDimensionless,
+	)
+
+	// SessionCreatedCountView is a view of the total number of sessions
+	// created by the pool.
+	//
+	// Together with SessionDestroyedCountView and OpenSessionCountView, it
+	// gives the churn of the pool, which OpenCensus cannot derive itself:
+	// the rate of created plus destroyed sessions over an interval, divided
+	// by the number of open sessions. A pool whose churn stays high keeps
+	// replacing its sessions, which usually means that they are not found
+	// or expire faster than they are used.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionCreatedCountView = &view.View{
+		Measure:     SessionCreatedCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// SessionDestroyedCountView is a view of the total number of sessions
+	// removed from the pool and deleted.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionDestroyedCountView = &view.View{
+		Measure:     SessionDestroyedCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// FinalizedSessionsCount is the number of sessions that were checked out
+	// of the pool and abandoned: their session handles were garbage
+	// collected without the sessions being returned to the pool. Any
+	// abandoned session points to a transaction or iterator that was not
+	// closed, such as a RowIterator that was not stopped. Abandoned sessions
+	// are not returned to the pool. It is only recorded for the sessions
+	// checked out while FinalizedSessionsCountView is enabled, and not if
+	// SessionPoolConfig.TrackSessionHandles is set.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	FinalizedSessionsCount = stats.Int64(
+		statsPrefix+"finalized_sessions_count",
+		"The number of abandoned sessions whose handle was garbage collected.",
+		stats.UnitDimensionless,
+	)
+
+	// FinalizedSessionsCountView is a view of the total number of abandoned
+	// sessions whose session handles were garbage collected.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	FinalizedSessionsCountView = &view.View{
+		Measure:     FinalizedSessionsCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// ConfiguredMinSessions is the SessionPoolConfig.MinOpened of the
+	// session pool of a client, recorded when the client is created.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredMinSessions = stats.Int64(
+		statsPrefix+"configured_min_sessions",
+		"The configured minimum number of opened sessions.",
+		stats.UnitDimensionless,
+	)
+
+	// ConfiguredMinSessionsView is a view of the last value of
+	// ConfiguredMinSessions.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredMinSessionsView = &view.View{
+		Measure:     ConfiguredMinSessions,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// ConfiguredMaxSessions is the SessionPoolConfig.MaxOpened of the
+	// session pool of a client, recorded when the client is created. Unlike
+	// MaxAllowedSessionsCount, which is part of the views enabled by
+	// EnableStatViews, it is recorded together with the rest of the
+	// configuration of the pool. A value of 0 means that the number of
+	// sessions is not limited.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredMaxSessions = stats.Int64(
+		statsPrefix+"configured_max_sessions",
+		"The configured maximum number of opened sessions, or 0 for no limit.",
+		stats.UnitDimensionless,
+	)
+
+	// ConfiguredMaxSessionsView is a view of the last value of
+	// ConfiguredMaxSessions.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredMaxSessionsView = &view.View{
+		Measure:     ConfiguredMaxSessions,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+
+	// ConfiguredWriteFraction is the SessionPoolConfig.WriteSessions of the
+	// session pool of a client, recorded when the client is created.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredWriteFraction = stats.Float64(
+		statsPrefix+"configured_write_fraction",
+		"The configured fraction of sessions prepared for write.",
+		stats.UnitDimensionless,
+	)
+
+	// ConfiguredWriteFractionView is a view of the last value of
+	// ConfiguredWriteFraction.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ConfiguredWriteFractionView = &view.View{
+		Measure:     ConfiguredWriteFraction,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// PoolExhaustedBlockDuration is the time in milliseconds session
+	// acquisitions spent waiting for a session while the pool was at its
+	// SessionPoolConfig.MaxOpened, and the sessions being created were
+	// already claimed by other waiters. It does not include waits for
+	// sessions that the pool was allowed to create, which are part of
+	// SessionAcquisitionLatency. A steadily
+	// growing total means that MaxOpened is too low for the concurrency of
+	// the application.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	PoolExhaustedBlockDuration = stats.Float64(
+		statsPrefix+"pool_exhausted_block_duration",
+		"The time acquisitions spent waiting for a session because the pool was at its maximum size.",
+		stats.UnitMilliseconds,
+	)
+
+	// PoolExhaustedBlockDurationView is a view of the total of
+	// PoolExhaustedBlockDuration values.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	PoolExhaustedBlockDurationView = &view.View{
+		Measure:     PoolExhaustedBlockDuration,
+		Aggregation: view.Sum(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// MaxQueryLatency is the highest query latency, in milliseconds, seen by
+	// a client since it was created or since its last ResetMaxQueryLatency.
+	// The latency of a query is the time from the first call to Next of its
+	// iterator until its first result set is received. It is recorded when
+	// the maximum grows, and as 0 when it is reset.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	MaxQueryLatency = stats.Int64(
+		statsPrefix+"max_query_latency",
+		"The highest query latency since the last reset.",
+		stats.UnitMilliseconds,
+	)
+
+	// MaxQueryLatencyView is a view of the last value of MaxQueryLatency.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	MaxQueryLatencyView = &view.View{
+		Measure:     MaxQueryLatency,
+		Aggregation: view.LastValue(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// ResultSetChunks is the number of partial result sets, or chunks, a
+	// streaming read or query returned, recorded once its results have been
+	// consumed, it failed or its iterator was stopped. A high number of
+	// chunks for little data points to streaming overhead.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ResultSetChunks = stats.Int64(
+		statsPrefix+"result_set_chunks",
+		"The number of chunks returned by streaming reads and queries.",
+		stats.UnitDimensionless,
+	)
+
+	// ResultSetChunksView is a view of the distribution of ResultSetChunks
+	// values, by method.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	ResultSetChunksView = &view.View{
+		Measure:     ResultSetChunks,
+		Aggregation: view.Distribution(0.0, 1.0, 2.0, 5.0, 10.0, 20.0, 50.0, 100.0, 200.0, 500.0, 1000.0, 5000.0, 10000.0),
+		TagKeys:     append(tagCommonKeys, tagKeyMethod),
+	}
+)
+
+var (
+	// UnavailableRetriesCount is the number of RPCs retried because they
+	// failed with UNAVAILABLE, as configured by the retry settings of the
+	// client. Streaming reads and queries, which resume their streams
+	// themselves, are not counted.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	UnavailableRetriesCount = stats.Int64(
+		statsPrefix+"unavailable_retries_count",
+		"The number of RPCs retried because they failed with UNAVAILABLE.",
+		stats.UnitDimensionless,
+	)
+
+	// UnavailableRetriesCountView is a view of the total number of RPCs
+	// retried because they failed with UNAVAILABLE, by gRPC method, such as
+	// "ExecuteSql". A rising count shows that the backend or the network is
+	// unstable, even while the retries hide the errors from the
+	// application.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	UnavailableRetriesCountView = &view.View{
+		Measure:     UnavailableRetriesCount,
+		Aggregation: view.Count(),
+		TagKeys:     append(tagCommonKeys, tagKeyMethod),
+	}
+)
+
+var (
+	// QueriesInFlight is the change in the number of queries in flight,
+	// recorded as 1 when a query is sent and -1 when its results have been
+	// consumed, it failed or its iterator was stopped.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	QueriesInFlight = stats.Int64(
+		statsPrefix+"queries_in_flight",
+		"The change in the number of queries in flight.",
+		stats.UnitDimensionless,
+	)
+
+	// QueriesInFlightView is a view of the number of queries in flight,
+	// whatever the number of sessions they use.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	QueriesInFlightView = &view.View{
+		Measure:     QueriesInFlight,
+		Aggregation: view.Sum(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+var (
+	// SessionPoolEvents is the number of session pool events recorded by
+	// applications and libraries with Client.RecordSessionPoolEvent.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolEvents = stats.Int64(
+		statsPrefix+"session_pool_events",
+		"The number of session pool events recorded by the application.",
+		stats.UnitDimensionless,
+	)
+
+	// SessionPoolEventsView is a view of the total number of session pool
+	// events recorded by the application, by event type.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	SessionPoolEventsView = &view.View{
+		Measure:     SessionPoolEvents,
+		Aggregation: view.Sum(),
+		TagKeys:     append(tagCommonKeys, tagKeyEventType),
+	}
+)
+
+var (
+	// AsyncRecordDroppedCount is the number of measurements dropped because
+	// the buffer of asynchronous recording was full. It is only recorded
+	// while asynchronous recording is enabled with EnableAsyncRecording.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AsyncRecordDroppedCount = stats.Int64(
+		statsPrefix+"async_record_dropped_count",
+		"The number of measurements dropped by asynchronous recording.",
+		stats.UnitDimensionless,
+	)
+
+	// AsyncRecordDroppedCountView is a view of the total number of
+	// measurements dropped by asynchronous recording.
+	// It is EXPERIMENTAL and subject to change or removal without notice.
+	AsyncRecordDroppedCountView = &view.View{
+		Measure:     AsyncRecordDroppedCount,
+		Aggregation: view.Count(),
+		TagKeys:     tagCommonKeys,
+	}
+)
+
+// statViews are the views enabled by EnableStatViews.
+var statViews = []*view.View{
+	OpenSessionCountView,
+	MaxAllowedSessionsCountView,
+	SessionsCountView,
+	MaxInUseSessionsCountView,
+	GetSessionTimeoutsCountView,
+	AcquiredSessionsCountView,
+	ReleasedSessionsCountView,
+}
+
+// EnableStatViews enables all views of metrics relate to session management.
+func EnableStatViews() error {
+	return view.Register(statViews...)
+}
+
+// EnableGfeLatencyView enables GFELatency metric
+//
+// GFELatency and GFEHeaderMissingCount are captured together, so enabling
+// only this view drops the GFEHeaderMissingCount data. Use
+// EnableGfeLatencyAndHeaderMissingCountViews to collect both.
+func EnableGfeLatencyView() error {
+	if err := view.Register(GFELatencyView); err != nil {
+		return err
+	}
+	setGFELatencyMetricsFlag(true)
+	return nil
+}
+
+// EnableGfeHeaderMissingCountView enables GFEHeaderMissingCount metric
+//
+// GFELatency and GFEHeaderMissingCount are captured together, so enabling
+// only this view drops the GFELatency data. Use
+// EnableGfeLatencyAndHeaderMissingCountViews to collect both.
+func EnableGfeHeaderMissingCountView() error {
+	if err := view.Register(GFEHeaderMissingCountView); err != nil {
+		return err
+	}
+	setGFELatencyMetricsFlag(true)
+	return nil
+}
+
+// EnableGfeLatencyAndHeaderMissingCountViews enables GFEHeaderMissingCount and GFELatency metric
+//
+// Either both views are registered or, if registering one of them fails,
+// neither is and GFE metrics stay disabled. GFE metrics are enabled for all
+// clients; use Client.EnableGfeLatencyMetrics to enable them for a single
+// client.
+func EnableGfeLatencyAndHeaderMissingCountViews() error {
+	if err := registerAllViews(GFELatencyView, GFEHeaderMissingCountView); err != nil {
+		return err
+	}
+	setGFELatencyMetricsFlag(true)
+	return nil
+}
+
+// registerAllViews registers the given views. If any view cannot be
+// registered, the views registered by this call are unregistered again.
+func registerAllViews(views ...*view.View) error {
+	var registered []*view.View
+	for _, v := range views {
+		existed := view.Find(v.Name) != nil
+		if err := view.Register(v); err != nil {
+			view.Unregister(registered...)
+			return err
+		}
+		if !existed {
+			registered = append(registered, v)
+		}
+	}
+	return nil
+}
+
+// EnableSessionPoolHitRatioViews enables the SessionPoolHitCount and
+// SessionPoolMissCount metrics. The ratio of hits to hits plus misses shows
+// how often the session pool can serve a request without waiting.
+func EnableSessionPoolHitRatioViews() error {
+	return enableViews(SessionPoolHitCountView, SessionPoolMissCountView)
+}
+
+// DisableSessionPoolHitRatioViews disables the SessionPoolHitCount and
+// SessionPoolMissCount metrics.
+func DisableSessionPoolHitRatioViews() {
+	disableViews(SessionPoolHitCountView, SessionPoolMissCountView)
+}
+
+// EnableSessionAcquisitionLatencyView enables the SessionAcquisitionLatency
+// metric.
+func EnableSessionAcquisitionLatencyView() error {
+	return enableViews(SessionAcquisitionLatencyView)
+}
+
+// DisableSessionAcquisitionLatencyView disables the SessionAcquisitionLatency
+// metric.
+func DisableSessionAcquisitionLatencyView() {
+	disableViews(SessionAcquisitionLatencyView)
+}
+
+// EnableSessionAcquisitionByTypeViews enables the
+// GetSessionTimeoutsCountByTypeView and SessionAcquisitionLatencyByTypeView
+// views, which break down session acquisition metrics by the type of session
+// that was requested. They are not enabled by EnableStatViews, as the extra
+// tag increases the number of exported time series.
+func EnableSessionAcquisitionByTypeViews() error {
+	return enableViews(GetSessionTimeoutsCountByTypeView, SessionAcquisitionLatencyByTypeView)
+}
+
+// DisableSessionAcquisitionByTypeViews disables the
+// GetSessionTimeoutsCountByTypeView and SessionAcquisitionLatencyByTypeView
+// views.
+func DisableSessionAcquisitionByTypeViews() {
+	disableViews(GetSessionTimeoutsCountByTypeView, SessionAcquisitionLatencyByTypeView)
+}
+
+// EnableBytesReturnedView enables the BytesReturned metric.
+func EnableBytesReturnedView() error {
+	return enableViews(BytesReturnedView)
+}
+
+// DisableBytesReturnedView disables the BytesReturned metric.
+func DisableBytesReturnedView() {
+	disableViews(BytesReturnedView)
+}
+
+// EnablePartitionCountView enables the PartitionCount metric.
+func EnablePartitionCountView() error {
+	return enableViews(PartitionCountView)
+}
+
+// DisablePartitionCountView disables the PartitionCount metric.
+func DisablePartitionCountView() {
+	disableViews(PartitionCountView)
+}
+
+// EnableDMLRowsAffectedView enables the DMLRowsAffected metric.
+func EnableDMLRowsAffectedView() error {
+	return enableViews(DMLRowsAffectedView)
+}
+
+// DisableDMLRowsAffectedView disables the DMLRowsAffected metric.
+func DisableDMLRowsAffectedView() {
+	disableViews(DMLRowsAffectedView)
+}
+
+// EnableCommitAttemptsView enables the CommitAttempts metric.
+func EnableCommitAttemptsView() error {
+	return enableViews(CommitAttemptsView)
+}
+
+// DisableCommitAttemptsView disables the CommitAttempts metric.
+func DisableCommitAttemptsView() {
+	disableViews(CommitAttemptsView)
+}
+
+// EnableTransactionDurationView enables the TransactionDuration metric.
+func EnableTransactionDurationView() error {
+	return enableViews(TransactionDurationView)
+}
+
+// DisableTransactionDurationView disables the TransactionDuration metric.
+func DisableTransactionDurationView() {
+	disableViews(TransactionDurationView)
+}
+
+// EnableTransactionCountView enables the TransactionCount metric.
+func EnableTransactionCountView() error {
+	return enableViews(TransactionCountView)
+}
+
+// DisableTransactionCountView disables the TransactionCount metric.
+func DisableTransactionCountView() {
+	disableViews(TransactionCountView)
+}
+
+// SetSlowTransactionThreshold makes TransactionDuration only record the
+// transactions that take at least d, so that the distribution of
+// TransactionDurationView holds the slow tail of the transactions only,
+// which cuts the volume of the metric for workloads made mostly of fast
+// transactions. Use TransactionCountView to count all transactions. A
+// threshold of 0, the default, records every transaction.
+// It is EXPERIMENTAL and subject to change or removal without notice.
+func SetSlowTransactionThreshold(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("spanner: invalid slow transaction threshold %v, must not be negative", d)
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	slowTransactionThreshold = d
+	return nil
+}
+
+// EnableOldestIdleSessionAgeView enables the OldestIdleSessionAge metric.
+func EnableOldestIdleSessionAgeView() error {
+	return enableViews(OldestIdleSessionAgeView)
+}
+
+// DisableOldestIdleSessionAgeView disables the OldestIdleSessionAge metric.
+func DisableOldestIdleSessionAgeView() {
+	disableViews(OldestIdleSessionAgeView)
+}
+
+// EnableAcquisitionQueueDepthView enables the AcquisitionQueueDepth metric.
+func EnableAcquisitionQueueDepthView() error {
+	return enableViews(AcquisitionQueueDepthView)
+}
+
+// DisableAcquisitionQueueDepthView disables the AcquisitionQueueDepth
+// metric.
+func DisableAcquisitionQueueDepthView() {
+	disableViews(AcquisitionQueueDepthView)
+}
+
+// EnableSessionKeepAlivePingCountView enables the SessionKeepAlivePingCount
+// metric.
+func EnableSessionKeepAlivePingCountView() error {
+	return enableViews(SessionKeepAlivePingCountView)
+}
+
+// DisableSessionKeepAlivePingCountView disables the
+// SessionKeepAlivePingCount metric.
+func DisableSessionKeepAlivePingCountView() {
+	disableViews(SessionKeepAlivePingCountView)
+}
+
+// EnableBatchCreateSessionsSizeView enables the BatchCreateSessionsSize
+// metric.
+func EnableBatchCreateSessionsSizeView() error {
+	return enableViews(BatchCreateSessionsSizeView)
+}
+
+// DisableBatchCreateSessionsSizeView disables the BatchCreateSessionsSize
+// metric.
+func DisableBatchCreateSessionsSizeView() {
+	disableViews(BatchCreateSessionsSizeView)
+}
+
+// EnableBatchCreateSessionsLatencyView enables the
+// BatchCreateSessionsLatency metric.
+func EnableBatchCreateSessionsLatencyView() error {
+	return enableViews(BatchCreateSessionsLatencyView)
+}
+
+// DisableBatchCreateSessionsLatencyView disables the
+// BatchCreateSessionsLatency metric.
+func DisableBatchCreateSessionsLatencyView() {
+	disableViews(BatchCreateSessionsLatencyView)
+}
+
+// EnableSessionHoldDurationView enables the SessionHoldDuration metric.
+func EnableSessionHoldDurationView() error {
+	return enableViews(SessionHoldDurationView)
+}
+
+// DisableSessionHoldDurationView disables the SessionHoldDuration metric.
+func DisableSessionHoldDurationView() {
+	disableViews(SessionHoldDurationView)
+}
+
+// EnableSessionNotFoundCountView enables the SessionNotFoundCount metric.
+func EnableSessionNotFoundCountView() error {
+	return enableViews(SessionNotFoundCountView)
+}
+
+// DisableSessionNotFoundCountView disables the SessionNotFoundCount metric.
+func DisableSessionNotFoundCountView() {
+	disableViews(SessionNotFoundCountView)
+}
+
+// EnableSessionChurnViews enables the SessionCreatedCount and
+// SessionDestroyedCount metrics, which give the churn of the session pool.
+func EnableSessionChurnViews() error {
+	return enableViews(SessionCreatedCountView, SessionDestroyedCountView)
+}
+
+// DisableSessionChurnViews disables the SessionCreatedCount and
+// SessionDestroyedCount metrics.
+func DisableSessionChurnViews() {
+	disableViews(SessionCreatedCountView, SessionDestroyedCountView)
+}
+
+// EnableFinalizedSessionsCountView enables the FinalizedSessionsCount metric.
+// While it is enabled, the session handles checked out of the pool are given
+// a finalizer, which adds a little garbage collection work.
+func EnableFinalizedSessionsCountView() error {
+	return enableViews(FinalizedSessionsCountView)
+}
+
+// DisableFinalizedSessionsCountView disables the FinalizedSessionsCount
+// metric.
+func DisableFinalizedSessionsCountView() {
+	disableViews(FinalizedSessionsCountView)
+}
+
+// EnablePoolExhaustedBlockDurationView enables the
+// PoolExhaustedBlockDuration metric.
+func EnablePoolExhaustedBlockDurationView() error {
+	return enableViews(PoolExhaustedBlockDurationView)
+}
+
+// DisablePoolExhaustedBlockDurationView disables the
+// PoolExhaustedBlockDuration metric.
+func DisablePoolExhaustedBlockDurationView() {
+	disableViews(PoolExhaustedBlockDurationView)
+}
+
+// EnableSessionPoolConfigViews enables the ConfiguredMinSessions,
+// ConfiguredMaxSessions and ConfiguredWriteFraction metrics. The
+// configuration of a session pool is recorded when its client is created, so
+// the views must be enabled before creating the clients to observe.
+func EnableSessionPoolConfigViews() error {
+	return enableViews(ConfiguredMinSessionsView, ConfiguredMaxSessionsView, ConfiguredWriteFractionView)
+}
+
+// DisableSessionPoolConfigViews disables the ConfiguredMinSessions,
+// ConfiguredMaxSessions and ConfiguredWriteFraction metrics.
+func DisableSessionPoolConfigViews() {
+	disableViews(ConfiguredMinSessionsView, ConfiguredMaxSessionsView, ConfiguredWriteFractionView)
+}
+
+// EnableMaxQueryLatencyView enables the MaxQueryLatency metric.
+func EnableMaxQueryLatencyView() error {
+	return enableViews(MaxQueryLatencyView)
+}
+
+// DisableMaxQueryLatencyView disables the MaxQueryLatency metric.
+func DisableMaxQueryLatencyView() {
+	disableViews(MaxQueryLatencyView)
+}
+
+// EnableResultSetChunksView enables the ResultSetChunks metric.
+func EnableResultSetChunksView() error {
+	return enableViews(ResultSetChunksView)
+}
+
+// DisableResultSetChunksView disables the ResultSetChunks metric.
+func DisableResultSetChunksView() {
+	disableViews(ResultSetChunksView)
+}
+
+// EnableUnavailableRetriesCountView enables the UnavailableRetriesCount
+// metric.
+func EnableUnavailableRetriesCountView() error {
+	return enableViews(UnavailableRetriesCountView)
+}
+
+// DisableUnavailableRetriesCountView disables the UnavailableRetriesCount
+// metric.
+func DisableUnavailableRetriesCountView() {
+	disableViews(UnavailableRetriesCountView)
+}
+
+// EnableQueriesInFlightView enables the QueriesInFlight metric.
+func EnableQueriesInFlightView() error {
+	return enableViews(QueriesInFlightView)
+}
+
+// DisableQueriesInFlightView disables the QueriesInFlight metric.
+func DisableQueriesInFlightView() {
+	disableViews(QueriesInFlightView)
+}
+
+// EnableSessionPoolEventsView enables the SessionPoolEvents metric.
+func EnableSessionPoolEventsView() error {
+	return enableViews(SessionPoolEventsView)
+}
+
+// DisableSessionPoolEventsView disables the SessionPoolEvents metric.
+func DisableSessionPoolEventsView() {
+	disableViews(SessionPoolEventsView)
+}
+
+// EnableAsyncRecordDroppedCountView enables the AsyncRecordDroppedCount
+// metric.
+func EnableAsyncRecordDroppedCountView() error {
+	return enableViews(AsyncRecordDroppedCountView)
+}
+
+// DisableAsyncRecordDroppedCountView disables the AsyncRecordDroppedCount
+// metric.
+func DisableAsyncRecordDroppedCountView() {
+	disableViews(AsyncRecordDroppedCountView)
+}
+
+// enableViews registers the given opt-in views and starts recording their
+// measures.
+func enableViews(views ...*view.View) error {
+	if err := view.Register(views...); err != nil {
+		return err
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	for _, v := range views {
+		enabledViews[v] = true
+	}
+	return nil
+}
+
+// disableViews stops recording the measures of the given opt-in views and
+// unregisters them.
+func disableViews(views ...*view.View) {
+	statsMu.Lock()
+	for _, v := range views {
+		delete(enabledViews, v)
+	}
+	statsMu.Unlock()
+	view.Unregister(views...)
+}
+
+// isViewEnabled reports whether the opt-in view v has been enabled, either
+// globally or on a caller-supplied meter.
+func isViewEnabled(v *view.View) bool {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	if enabledViews[v] {
+		return true
+	}
+	for _, views := range meterViews {
+		if views[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableViewsOnMeter registers the given views, such as OpenSessionCountView
+// or the opt-in SessionHoldDurationView, on meter instead of the default
+// meter, and starts recording the measurements of this package to meter as
+// well. This keeps the data of the views separate from the default meter,
+// for example to isolate tests. The meter must be started by the caller, and
+// its data read with meter.RetrieveData or exported with its own exporters.
+//
+// OpenCensus measurements are not associated with a meter, so every meter
+// with views enabled receives the measurements of all clients in the
+// process. To separate the metrics of several clients or databases, filter
+// them by the client_id and database tags. The GFE views additionally
+// require GFE metrics to be enabled with Client.EnableGfeLatencyMetrics.
+func EnableViewsOnMeter(meter view.Meter, views ...*view.View) error {
+	if err := meter.Register(views...); err != nil {
+		return err
+	}
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	if meterViews[meter] == nil {
+		meterViews[meter] = map[*view.View]bool{}
+	}
+	for _, v := range views {
+		meterViews[meter][v] = true
+	}
+	return nil
+}
+
+// DisableViewsOnMeter unregisters the given views from meter. Measurements
+// are no longer recorded to meter once none of its views enabled through
+// EnableViewsOnMeter remain.
+func DisableViewsOnMeter(meter view.Meter, views ...*view.View) {
+	statsMu.Lock()
+	for _, v := range views {
+		delete(meterViews[meter], v)
+	}
+	if len(meterViews[meter]) == 0 {
+		delete(meterViews, meter)
+	}
+	statsMu.Unlock()
+	meter.Unregister(views...)
+}
+
+// resetViews clears the data aggregated so far by every view of this package
+// that is enabled, whether globally or on a meter through EnableViewsOnMeter,
+// by unregistering and registering it again. The views stay enabled. It is
+// exported to tests by spannertest.ResetViewsForTesting.
+func resetViews() error {
+	statsMu.RLock()
+	var views []*view.View
+	for v := range enabledViews {
+		views = append(views, v)
+	}
+	meters := make(map[view.Meter][]*view.View, len(meterViews))
+	for meter, vs := range meterViews {
+		for v := range vs {
+			meters[meter] = append(meters[meter], v)
+		}
+	}
+	statsMu.RUnlock()
+	// The views of EnableStatViews and the GFE views are registered without
+	// being tracked, so reset them if they are registered.
+	untracked := append([]*view.View{GFELatencyView, GFEHeaderMissingCountView}, statViews...)
+	for _, v := range untracked {
+		if !containsView(views, v) && view.Find(v.Name) == v {
+			views = append(views, v)
+		}
+	}
+
+	view.Unregister(views...)
+	if err := view.Register(views...); err != nil {
+		return err
+	}
+	for meter, vs := range meters {
+		meter.Unregister(vs...)
+		if err := meter.Register(vs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containsView reports whether views holds v.
+func containsView(views []*view.View, v *view.View) bool {
+	for _, w := range views {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+func getGFELatencyMetricsFlag() bool {
+	statsMu.RLock()
+	defer statsMu.RUnlock()
+	return gfeLatencyMetricsEnabled
+}
+
+// setGFELatencyMetricsFlag enables or disables GFE metrics for all open
+// clients and for clients created from now on.
+func setGFELatencyMetricsFlag(enable bool) {
+	statsMu.Lock()
+	gfeLatencyMetricsEnabled = enable
+	for sc := range gfeLatencyMetricsClients {
+		sc.gfeLatencyMetrics = enable
+	}
+	statsMu.Unlock()
+}
+
+// registerGFELatencyMetricsClient starts tracking sc, initializing its GFE
+// metrics flag from the package-level flag.
+func registerGFELatencyMetricsClient(sc *sessionClient) {
+	statsMu.Lock()
+	sc.gfeLatencyMetrics = gfeLatencyMetricsEnabled
+	gfeLatencyMetricsClients[sc] = true
+	statsMu.Unlock()
+}
+
+// unregisterGFELatencyMetricsClient stops tracking sc.
+func unregisterGFELatencyMetricsClient(sc *sessionClient) {
+	statsMu.Lock()
+	delete(gfeLatencyMetricsClients, sc)
+	statsMu.Unlock()
+}
+
+// DisableGfeLatencyAndHeaderMissingCountViews disables GFEHeaderMissingCount and GFELatency metric
+func DisableGfeLatencyAndHeaderMissingCountViews() {
+	setGFELatencyMetricsFlag(false)
+	view.Unregister(
+		GFELatencyView,
+		GFEHeaderMissingCountView,
+	)
+}
+
+// captureGFELatencyStats records the GFE latency reported in the
+// server-timing header of md, or a missing header. Callers only trace the
+// returned error, as failing to record a metric should not fail the RPC.
+func captureGFELatencyStats(ctx context.Context, md metadata.MD, keyMethod string) error {
+	if len(md.Get("server-timing")) == 0 {
+		recordStat(ctx, GFEHeaderMissingCount, 1)
+		return nil
+	}
+	serverTiming := md.Get("server-timing")[0]
+	if !strings.HasPrefix(serverTiming, "gfet4t7; dur=") {
+		return fmt.Errorf("spanner: unexpected server-timing header %q for method %s", serverTiming, keyMethod)
+	}
+	gfeLatency, err := strconv.Atoi(strings.TrimPrefix(serverTiming, "gfet4t7; dur="))
+	if err != nil {
+		return xerrors.Errorf("spanner: failed to parse server-timing header %q for method %s: %w", serverTiming, keyMethod, err)
+	}
+	// Record GFE latency with OpenCensus.
+	ctx = tag.NewContext(ctx, tag.FromContext(ctx))
+	ctx, err = tag.New(ctx, tag.Insert(tagKeyMethod, keyMethod))
+	if err != nil {
+		return err
+	}
+	return recordStatWithSpanContext(ctx, GFELatency, int64(gfeLatency))
+}
+
+func checkCommonTagsGFELatency(t *testing.T, m map[tag.Key]string) {
+	// We only check prefix because client ID increases if we create
+	// multiple clients for the same database.
+	if !strings.HasPrefix(m[tagKeyClientID], "client") {
+		t.Fatalf("Incorrect client ID: %v", m[tagKeyClientID])
+	}
+	if m[tagKeyLibVersion] != version.Repo {
+		t.Fatalf("Incorrect library version: %v", m[tagKeyLibVersion])
+	}
+}
+
+func createContextAndCaptureGFELatencyMetrics(ctx context.Context, ct *commonTags, md metadata.MD, keyMethod string) error {
+	var ctxGFE, err = tag.New(ctx,
+		tag.Upsert(tagKeyClientID, ct.clientID),
+		tag.Upsert(tagKeyDatabase, ct.database),
+		tag.Upsert(tagKeyInstance, ct.instance),
+		tag.Upsert(tagKeyLibVersion, ct.libVersion),
+	)
+	if err != nil {
+		return err
+	}
+	return captureGFELatencyStats(ctxGFE, md, keyMethod)
+}
+
+// mergeTagMap returns a copy of ctx tagged with the common tags in m. Other
+// tags of ctx, such as tags of the application, are preserved.
+func mergeTagMap(ctx context.Context, m *tag.Map, mutators ...tag.Mutator) (context.Context, error) {
+	var all []tag.Mutator
+	for _, k := range tagCommonKeys {
+		if v, ok := m.Value(k); ok {
+			all = append(all, tag.Upsert(k, v))
+		}
+	}
+	return tag.New(ctx, append(all, mutators...)...)
+}
+
+// contextWithCommonTags returns a copy of ctx tagged with the common tags ct
+// and the given method.
+func contextWithCommonTags(ctx context.Context, ct *commonTags, method string) (context.Context, error) {
+	return tag.New(ctx,
+		tag.Upsert(tagKeyClientID, ct.clientID),
+		tag.Upsert(tagKeyDatabase, ct.database),
+		tag.Upsert(tagKeyInstance, ct.instance),
+		tag.Upsert(tagKeyLibVersion, ct.libVersion),
+		tag.Upsert(tagKeyMethod, method),
+	)
+}
+
+// recordPartitionCount records the number of partitions returned by method,
+// if the PartitionCount metric is enabled.
+func recordPartitionCount(ctx context.Context, ct *commonTags, method string, n int) {
+	if ct == nil || !isViewEnabled(PartitionCountView) {
+		return
+	}
+	ctx, err := contextWithCommonTags(ctx, ct, method)
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for PartitionCount: %v", err)
+		return
+	}
+	recordStat(ctx, PartitionCount, int64(n))
+}
+
+// recordQueryLatency records d as MaxQueryLatency if it is the highest query
+// latency of the client since its last reset, if the MaxQueryLatency metric
+// is enabled. The maximum is updated under its own lock, and recorded after
+// the lock is released.
+func recordQueryLatency(ctx context.Context, ct *commonTags, d time.Duration) {
+	if ct == nil || !isViewEnabled(MaxQueryLatencyView) {
+		return
+	}
+	ms := int64(d / time.Millisecond)
+	sc := ct.sc
+	sc.maxQueryLatencyMu.Lock()
+	if ms <= sc.maxQueryLatency {
+		sc.maxQueryLatencyMu.Unlock()
+		return
+	}
+	sc.maxQueryLatency = ms
+	sc.maxQueryLatencyMu.Unlock()
+	ctx, err := contextWithCommonTags(ctx, ct, "query")
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for MaxQueryLatency: %v", err)
+		return
+	}
+	recordStat(ctx, MaxQueryLatency, ms)
+}
+
+// recordDMLRowsAffected records n rows affected by the DML statements
+// executed by method, tagged by whether n is a lower bound, if the
+// DMLRowsAffected metric is enabled.
+func recordDMLRowsAffected(ctx context.Context, ct *commonTags, method string, n int64, lowerBound bool) {
+	if ct == nil || !isViewEnabled(DMLRowsAffectedView) {
+		return
+	}
+	rowCount := "exact"
+	if lowerBound {
+		rowCount = "lower_bound"
+	}
+	ctx, err := contextWithCommonTags(ctx, ct, method)
+	if err == nil {
+		ctx, err = tag.New(ctx, tag.Upsert(tagKeyRowCount, rowCount))
+	}
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for DMLRowsAffected: %v", err)
+		return
+	}
+	recordStat(ctx, DMLRowsAffected, n)
+}
+
+// recordTransactionDuration records a read/write transaction by
+// TransactionCount, and the time it took by TransactionDuration if it took at
+// least the slow transaction threshold, tagged by its outcome as given by
+// err, for the metrics that are enabled.
+func recordTransactionDuration(ctx context.Context, ct *commonTags, d time.Duration, err error) {
+	if ct == nil {
+		return
+	}
+	statsMu.RLock()
+	threshold := slowTransactionThreshold
+	statsMu.RUnlock()
+	count := isViewEnabled(TransactionCountView)
+	duration := d >= threshold && isViewEnabled(TransactionDurationView)
+	if !count && !duration {
+		return
+	}
+	outcome := "committed"
+	if err != nil {
+		outcome = "failed"
+		if ErrCode(err) == codes.Aborted {
+			outcome = "aborted"
+		}
+	}
+	ctx, tagErr := contextWithCommonTags(ctx, ct, "ReadWriteTransaction")
+	if tagErr == nil {
+		ctx, tagErr = tag.New(ctx, tag.Upsert(tagKeyOutcome, outcome))
+	}
+	if tagErr != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for TransactionDuration: %v", tagErr)
+		return
+	}
+	if count {
+		recordStat(ctx, TransactionCount, 1)
+	}
+	if duration {
+		recordStat(ctx, TransactionDuration, int64(d/time.Millisecond))
+	}
+}
+
+// recordCommitAttempts records the number of attempts a read/write
+// transaction took to commit, if the CommitAttempts metric is enabled.
+func recordCommitAttempts(ctx context.Context, ct *commonTags, attempts int) {
+	if ct == nil || !isViewEnabled(CommitAttemptsView) {
+		return
+	}
+	ctx, err := contextWithCommonTags(ctx, ct, "ReadWriteTransaction")
+	if err != nil {
+		trace.TracePrintf(ctx, nil, "Error in adding tags for CommitAttempts: %v", err)
+		return
+	}
+	recordStat(ctx, CommitAttempts, int64(attempts))
 }
 
 func getCommonTags(sc *sessionClient) *commonTags {
@@ -306,6 +1751,7 @@ func getCommonTags(sc *sessionClient) *commonTags {
 		return nil
 	}
 	return &commonTags{
+		sc:         sc,
 		clientID:   sc.id,
 		database:   database,
 		instance:   instance,
@@ -315,6 +1761,8 @@ func getCommonTags(sc *sessionClient) *commonTags {
 
 // commonTags are common key-value pairs of data associated with the GFELatency measure
 type commonTags struct {
+	// Session client of the Client the tags belong to
+	sc *sessionClient
 	// Client ID
 	clientID string
 	// Database Name
@@ -174,7 +174,7 @@ func (t *txReadOnly) ReadWithOptions(ctx context.Context, table string, keys Key
 		prio = opts.Priority
 		requestTag = opts.RequestTag
 	}
-	return streamWithReplaceSessionFunc(
+	ri = streamWithReplaceSessionFunc(
 		contextWithOutgoingMetadata(ctx, sh.getMetadata()),
 		sh.session.logger,
 		func(ctx context.Context, resumeToken []byte) (streamingReceiver, error) {
@@ -194,7 +194,7 @@ func (t *txReadOnly) ReadWithOptions(ctx context.Context, table string, keys Key
 				return client, err
 			}
 			md, err := client.Header()
-			if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+			if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 				if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "ReadWithOptions"); err != nil {
 					trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 				}
@@ -205,6 +205,8 @@ func (t *txReadOnly) ReadWithOptions(ctx context.Context, table string, keys Key
 		t.setTimestamp,
 		t.release,
 	)
+	ri.ct, ri.method = t.ct, "ReadWithOptions"
+	return ri
 }
 
 // errRowNotFound returns error for not being able to read the row identified by
@@ -391,7 +393,7 @@ func (t *txReadOnly) query(ctx context.Context, statement Statement, options Que
 		return &RowIterator{err: err}
 	}
 	client := sh.getClient()
-	return streamWithReplaceSessionFunc(
+	ri = streamWithReplaceSessionFunc(
 		contextWithOutgoingMetadata(ctx, sh.getMetadata()),
 		sh.session.logger,
 		func(ctx context.Context, resumeToken []byte) (streamingReceiver, error) {
@@ -402,7 +404,7 @@ func (t *txReadOnly) query(ctx context.Context, statement Statement, options Que
 				return client, err
 			}
 			md, err := client.Header()
-			if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+			if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 				if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "query"); err != nil {
 					trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 				}
@@ -412,6 +414,8 @@ func (t *txReadOnly) query(ctx context.Context, statement Statement, options Que
 		t.replaceSessionFunc,
 		t.setTimestamp,
 		t.release)
+	ri.ct, ri.method = t.ct, "query"
+	return ri
 }
 
 func (t *txReadOnly) prepareExecuteSQL(ctx context.Context, stmt Statement, options QueryOptions) (*sppb.ExecuteSqlRequest, *sessionHandle, error) {
@@ -577,7 +581,7 @@ func (t *ReadOnlyTransaction) begin(ctx context.Context) error {
 			},
 		}, gax.WithGRPCOptions(grpc.Header(&md)))
 
-		if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+		if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 			if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "begin_BeginTransaction"); err != nil {
 				trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 			}
@@ -931,7 +935,7 @@ func (t *ReadWriteTransaction) update(ctx context.Context, stmt Statement, opts
 	var md metadata.MD
 	resultSet, err := sh.getClient().ExecuteSql(contextWithOutgoingMetadata(ctx, sh.getMetadata()), req, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+	if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "update"); err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", err)
 		}
@@ -942,7 +946,13 @@ func (t *ReadWriteTransaction) update(ctx context.Context, stmt Statement, opts
 	if resultSet.Stats == nil {
 		return 0, spannerErrorf(codes.InvalidArgument, "query passed to Update: %q", stmt.SQL)
 	}
-	return extractRowCount(resultSet.Stats)
+	rowCount, err = extractRowCount(resultSet.Stats)
+	if err != nil {
+		return 0, err
+	}
+	_, lowerBound := resultSet.Stats.RowCount.(*sppb.ResultSetStats_RowCountLowerBound)
+	recordDMLRowsAffected(ctx, t.ct, "Update", rowCount, lowerBound)
+	return rowCount, nil
 }
 
 // BatchUpdate groups one or more DML statements and sends them to Spanner in a
@@ -1006,7 +1016,7 @@ func (t *ReadWriteTransaction) batchUpdateWithOptions(ctx context.Context, stmts
 		RequestOptions: createRequestOptions(opts.Priority, opts.RequestTag, t.txOpts.TransactionTag),
 	}, gax.WithGRPCOptions(grpc.Header(&md)))
 
-	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
+	if t.ct != nil && t.ct.sc.gfeLatencyMetricsEnabled() && md != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "batchUpdateWithOptions"); err != nil {
 			trace.TracePrintf(ctx, nil, "Error in recording GFE Latency. Try disabling and rerunning. Error: %v", ToSpannerError(err))
 		}
@@ -1016,12 +1026,17 @@ func (t *ReadWriteTransaction) batchUpdateWithOptions(ctx context.Context, stmts
 	}
 
 	var counts []int64
+	var total int64
 	for _, rs := range resp.ResultSets {
 		count, err := extractRowCount(rs.Stats)
 		if err != nil {
 			return nil, err
 		}
 		counts = append(counts, count)
+		total += count
+	}
+	if len(counts) > 0 {
+		recordDMLRowsAffected(ctx, t.ct, "BatchUpdate", total, false)
 	}
 	if resp.Status != nil && resp.Status.Code != 0 {
 		return counts, spannerErrorf(codes.Code(uint32(resp.Status.Code)), resp.Status.Message)